@@ -1,10 +1,15 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/labstack/gommon/color"
 	"github.com/mkideal/cli"
@@ -23,8 +28,44 @@ func init() {
 		cli.Tree(removeCommand),
 		cli.Tree(listCommand),
 		cli.Tree(findCommand),
+		cli.Tree(generateCommand),
 		cli.Tree(upgradeCommand),
 		cli.Tree(infoCommand),
+		cli.Tree(editCommand),
+		cli.Tree(moveCommand),
+		cli.Tree(tagCommand,
+			cli.Tree(tagAddCommand),
+			cli.Tree(tagRemoveCommand),
+			cli.Tree(tagListCommand),
+		),
+		cli.Tree(favoriteCommand),
+		cli.Tree(folderCommand,
+			cli.Tree(folderMoveCommand),
+			cli.Tree(folderRenameCommand),
+			cli.Tree(folderRemoveCommand),
+		),
+		cli.Tree(expiringCommand),
+		cli.Tree(auditCommand),
+		cli.Tree(breachCommand),
+		cli.Tree(doctorCommand),
+		cli.Tree(trashCommand,
+			cli.Tree(trashListCommand),
+			cli.Tree(trashRestoreCommand),
+			cli.Tree(trashEmptyCommand),
+			cli.Tree(trashConfigCommand),
+		),
+		cli.Tree(templateCommand,
+			cli.Tree(templateListCommand),
+			cli.Tree(templateImportCommand),
+			cli.Tree(templateRemoveCommand),
+		),
+		cli.Tree(dedupeCommand),
+		cli.Tree(rotateCommand),
+		cli.Tree(deriveCommand),
+		cli.Tree(boxCommand,
+			cli.Tree(boxInfoCommand),
+			cli.Tree(boxConfigCommand),
+		),
 	)
 }
 
@@ -64,6 +105,26 @@ func (cfg Config) Debug() bool {
 	return cfg.EnableDebug
 }
 
+// NoMasterConfig is Config of commands which don't need the master password
+type NoMasterConfig struct {
+	EnableDebug bool `cli:"debug" usage:"Enable debug mode" dft:"false"`
+}
+
+// Filename returns password data filename
+func (cfg NoMasterConfig) Filename() string {
+	return Config{}.Filename()
+}
+
+// MasterPassword returns empty master password
+func (cfg NoMasterConfig) MasterPassword() string {
+	return ""
+}
+
+// Debug returns debug mode
+func (cfg NoMasterConfig) Debug() bool {
+	return cfg.EnableDebug
+}
+
 var box *core.Box
 
 //--------------
@@ -129,13 +190,17 @@ var helpCommand = cli.HelpCommand("Display help information")
 type initCommandT struct {
 	cli.Helper2
 	Config
-	Update bool `cli:"u,update" usage:"Whether to update the master password" dft:"false"`
+	Update   bool `cli:"u,update" usage:"Whether to update the master password" dft:"false"`
+	MinScore int  `cli:"min-score" usage:"Minimum strength score(0-4) of the master password" dft:"3"`
 }
 
 func (argv *initCommandT) Validate(ctx *cli.Context) error {
 	if argv.Filename() == "" {
 		return fmt.Errorf("FILE is empty")
 	}
+	if argv.MinScore < 0 || argv.MinScore > 4 {
+		return fmt.Errorf("min score must be in [0,4]")
+	}
 	return nil
 }
 
@@ -156,6 +221,9 @@ var initCommand = &cli.Command{
 		if argv.Master != string(cpw) {
 			return fmt.Errorf(ctx.Color().Red("master password mismatched"))
 		}
+		if err := core.CheckStrength(argv.Master, argv.MinScore); err != nil {
+			return err
+		}
 
 		if _, err := os.Lstat(argv.Filename()); err != nil {
 			if os.IsNotExist(err) {
@@ -178,6 +246,15 @@ var initCommand = &cli.Command{
 	Fn: func(ctx *cli.Context) error {
 		argv := ctx.Argv().(*initCommandT)
 		if argv.Update {
+			if ids := box.DerivedIDs(); len(ids) > 0 {
+				ok, err := prompt.Ask(fmt.Sprintf("%d derived passwords will change with the master password, continue? [y/N] ", len(ids)), false)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return nil
+				}
+			}
 			pw, err := prompt.Password("Type a new master password: ")
 			if err != nil {
 				return err
@@ -189,6 +266,9 @@ var initCommand = &cli.Command{
 			if string(pw) != string(cpw) {
 				return fmt.Errorf(ctx.Color().Red("new master password mismatched"))
 			}
+			if err := core.CheckStrength(string(pw), argv.MinScore); err != nil {
+				return err
+			}
 			return box.Update(string(pw))
 		}
 		return nil
@@ -202,15 +282,287 @@ type setCommandT struct {
 	cli.Helper2
 	Config
 	core.Password
-	Pw  string `pw:"p,password" usage:"The password you decided to use" name:"PASSWORD" prompt:"Type the password"`
-	Cpw string `pw:"C,confirm" usage:"Confirm password which must be same as PASSWORD" prompt:"Repeat the password"`
+	Pw          string            `pw:"p,password" usage:"The password you decided to use" name:"PASSWORD"`
+	Cpw         string            `pw:"C,confirm" usage:"Confirm password which must be same as PASSWORD"`
+	FieldValues map[string]string `cli:"F,field" usage:"Kind-specific field, e.g. -F expiry=01/27, value @FILE reads from file" name:"NAME=VALUE"`
+	Template    string            `cli:"t,template" usage:"Use the template and prompt for each field"`
+	Expires     string            `cli:"expires" usage:"Expiry date of password(YYYY-MM-DD)" name:"DATE"`
+	URLs        []string          `cli:"url" usage:"URL with match rule formatted as [MATCH:]URL, MATCH is one of exact,prefix,regex,host,domain(default)" name:"URL"`
+	Unset       []string          `cli:"unset" usage:"Clear the field when updating, e.g. --unset site" name:"FIELD"`
+	New         bool              `cli:"new" usage:"Add a new password even if it duplicates existing passwords" dft:"false"`
+	Update      bool              `cli:"update" usage:"Update the only password found by category and account instead of --id" dft:"false"`
+	Policy      map[string]string `cli:"policy" usage:"Policy of site used by rotate, KEY is one of length,max-length,classes(e.g. dcCs),forbidden,pattern" name:"KEY=VALUE"`
+	Generate    int               `cli:"g,generate" usage:"Generate the password of the length instead of prompting, 0 means length of the policy" name:"LENGTH"`
+	GenClasses  string            `cli:"gen-classes" usage:"Character classes of generated password, composed of d(digit),c(lowercase),C(uppercase),s(special)" name:"CLASSES"`
+	Reveal      bool              `cli:"reveal" usage:"Print the generated password once" dft:"false"`
+	Copy        bool              `cli:"copy" usage:"Copy the generated password to clipboard" dft:"false"`
+}
+
+// setFlags maps flags of set command to names of fields
+var setFlags = map[string]string{
+	"--category": core.FieldCategory,
+	"--account":  core.FieldAccount,
+	"--site":     core.FieldSite,
+	"--tag":      core.FieldTags,
+	"--hidden":   core.FieldHidden,
+	"--kind":     core.FieldKind,
+	"--alias":    core.FieldAlias,
+	"--template": core.FieldKind,
+	"--expires":  core.FieldExpires,
+	"--url":      core.FieldURLs,
+	"--policy":   core.FieldPolicy,
+}
+
+// markSet marks fields specified by flags, so updating copies them even if
+// they are empty or false, e.g. --hidden=false
+func (argv *setCommandT) markSet(ctx *cli.Context) {
+	for flag, name := range setFlags {
+		if ctx.IsSet(flag) {
+			argv.Password.MarkSet(name)
+		}
+	}
+	for name := range argv.FieldValues {
+		argv.Password.MarkSet(name)
+	}
+	for _, name := range argv.Unset {
+		argv.Password.Unset(name)
+	}
 }
 
 func (argv *setCommandT) Validate(ctx *cli.Context) error {
+	if argv.Template != "" {
+		if argv.Kind != "" && argv.Kind != argv.Template {
+			return fmt.Errorf("kind %s conflicts with template %s", argv.Kind, argv.Template)
+		}
+		argv.Kind = argv.Template
+	}
+	if argv.Expires != "" {
+		t, err := core.ParseDate(argv.Expires)
+		if err != nil {
+			return fmt.Errorf("invalid expiry date %s", argv.Expires)
+		}
+		argv.Password.ExpiresAt = t.Unix()
+	}
+	for _, s := range argv.URLs {
+		rule, err := core.ParseURLRule(s)
+		if err != nil {
+			return err
+		}
+		argv.Password.URLs = append(argv.Password.URLs, rule)
+	}
+	if len(argv.Policy) > 0 {
+		policy, err := core.ParsePolicy(argv.Policy)
+		if err != nil {
+			return err
+		}
+		argv.Password.Policy = policy
+	}
+	if argv.Pw != "" && argv.Cpw != "" && argv.Pw != argv.Cpw {
+		return fmt.Errorf("passwords mismatched")
+	}
+	if ctx.IsSet("--generate") {
+		if _, ok := argv.FieldValues["password"]; ok || argv.Pw != "" {
+			return fmt.Errorf("--generate conflicts with --password")
+		}
+		if argv.Generate < 0 {
+			return fmt.Errorf("invalid length %d", argv.Generate)
+		}
+	} else if argv.GenClasses != "" || argv.Reveal || argv.Copy {
+		return fmt.Errorf("--gen-classes, --reveal and --copy are used with --generate")
+	}
+	return nil
+}
+
+// generate generates the password by policy of the password, or the stored
+// policy when updating, --generate and --gen-classes override the policy
+func (argv *setCommandT) generate() (string, error) {
+	policy, kindName := argv.Password.Policy, argv.Kind
+	id, err := argv.target()
+	if err != nil {
+		return "", err
+	}
+	if id != "" {
+		old, err := box.Get(id)
+		if err != nil {
+			return "", err
+		}
+		if policy == nil && !argv.Password.IsSet(core.FieldPolicy) {
+			policy = old.Policy
+		}
+		if !argv.Password.IsSet(core.FieldKind) {
+			kindName = old.Kind
+		}
+	}
+	kind, err := box.LookupKind(kindName)
+	if err != nil {
+		return "", err
+	}
+	if spec, ok := kind.Field("password"); !ok || spec.Type != core.FieldText {
+		return "", fmt.Errorf("password of kind %s can't be generated", kind.Name)
+	}
+	p := core.DefaultPolicy
+	if policy != nil {
+		p = *policy
+	}
+	if argv.Generate > 0 {
+		p.Length, p.Pattern = argv.Generate, ""
+		if p.MaxLength > 0 && p.Length > p.MaxLength {
+			return "", fmt.Errorf("length %d exceeds max length %d of the policy", p.Length, p.MaxLength)
+		}
+	}
+	if argv.GenClasses != "" {
+		p.Classes = argv.GenClasses
+	}
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+	return p.Generate()
+}
+
+// target returns id of the password to update, which is --id or the only
+// password found by category and account if --update specified, empty means adding
+func (argv *setCommandT) target() (string, error) {
+	if !argv.Update || argv.ID != "" {
+		return argv.ID, nil
+	}
+	return box.FindByAccount(argv.Category, argv.PlainAccount)
+}
+
+// readPassword prompts for the password if the kind requires it and it's not specified,
+// updating prompts only if no field is specified
+func (argv *setCommandT) readPassword() error {
+	if _, ok := argv.FieldValues["password"]; ok {
+		argv.Pw = argv.FieldValues["password"]
+		argv.Cpw = argv.Pw
+	}
+	if argv.ID != "" && argv.Pw == "" && argv.Password.NumSet() > 0 {
+		return nil
+	}
+	kind, err := box.LookupKind(argv.Kind)
+	if err != nil {
+		return err
+	}
+	spec, ok := kind.Field("password")
+	if !ok || (!spec.Required && argv.Pw == "") {
+		return nil
+	}
+	if argv.Pw == "" {
+		pw, err := prompt.Password(kind.Name + " " + strings.ToLower(spec.Usage) + ": ")
+		if err != nil {
+			return err
+		}
+		argv.Pw = pw
+	}
+	if argv.Cpw == "" {
+		cpw, err := prompt.Password("Repeat the " + strings.ToLower(spec.Usage) + ": ")
+		if err != nil {
+			return err
+		}
+		argv.Cpw = cpw
+	}
 	if argv.Pw != argv.Cpw {
 		return fmt.Errorf("passwords mismatched")
 	}
-	return core.CheckPassword(argv.Pw)
+	if kind.Name == core.DefaultKind && !core.IsReference(argv.Pw) {
+		return core.CheckPassword(argv.Pw)
+	}
+	return nil
+}
+
+// warnWeakPassword warns if the password of login, token or db is weak
+func (argv *setCommandT) warnWeakPassword(ctx *cli.Context) {
+	if argv.Pw == "" || core.IsReference(argv.Pw) {
+		return
+	}
+	kind, err := box.LookupKind(argv.Kind)
+	if err != nil {
+		return
+	}
+	if spec, ok := kind.Field("password"); !ok || spec.Type != core.FieldText {
+		return
+	}
+	if s := core.EstimateStrength(argv.Pw, argv.PlainAccount, argv.Category, argv.Site); s.Score < core.RecommendedScore {
+		ctx.String("%s\n", ctx.Color().Yellow("warning: weak password("+s.String()+")"))
+	}
+}
+
+// readFields sets kind-specific fields, value prefixed with @ is read from file
+func (argv *setCommandT) readFields() error {
+	for name, value := range argv.FieldValues {
+		if strings.HasPrefix(value, "@") {
+			data, err := ioutil.ReadFile(value[1:])
+			if err != nil {
+				return err
+			}
+			value = string(data)
+		}
+		argv.Password.SetField(name, value)
+	}
+	return nil
+}
+
+// promptFields prompts for each field of the template which is not specified
+func (argv *setCommandT) promptFields() error {
+	kind, err := box.LookupKind(argv.Template)
+	if err != nil {
+		return err
+	}
+	for _, spec := range kind.Fields {
+		if spec.Name == "password" || argv.Password.GetField(spec.Name) != "" {
+			continue
+		}
+		text := fmt.Sprintf("%s(%s): ", spec.Name, spec.Type)
+		if spec.Usage != "" {
+			text = fmt.Sprintf("%s(%s, %s): ", spec.Name, spec.Type, spec.Usage)
+		}
+		var value string
+		if spec.Secret {
+			value, err = prompt.Password(text)
+		} else {
+			value, err = prompt.Prompt(text, spec.Required)
+		}
+		if err != nil {
+			return err
+		}
+		argv.Password.SetField(spec.Name, value)
+	}
+	return nil
+}
+
+// add adds the password, asks whether to update the duplicated password or add
+// a new one if the password duplicates existing passwords
+func (argv *setCommandT) add(ctx *cli.Context) (string, bool, error) {
+	if argv.New {
+		return box.AddDuplicate(&argv.Password)
+	}
+	id, new, err := box.Add(&argv.Password)
+	dup, ok := err.(*core.DuplicateError)
+	if !ok {
+		return id, new, err
+	}
+	ctx.String(dup.Error())
+	if len(dup.IDs) > 1 {
+		ok, err := prompt.Ask("Add a new password anyway? [y/N] ", false)
+		if err != nil {
+			return "", false, err
+		}
+		if !ok {
+			return "", false, fmt.Errorf("update one of duplicated passwords by --id")
+		}
+		return box.AddDuplicate(&argv.Password)
+	}
+	answer, err := prompt.PromptDefault("Update it(u), add a new one(n) or abort(a)? [u/n/A] ", "a")
+	if err != nil {
+		return "", false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "u":
+		argv.Password.ID = dup.IDs[0]
+		return box.Add(&argv.Password)
+	case "n":
+		return box.AddDuplicate(&argv.Password)
+	}
+	return "", false, fmt.Errorf("aborted")
 }
 
 var setCommand = &cli.Command{
@@ -225,8 +577,48 @@ var setCommand = &cli.Command{
 
 	Fn: func(ctx *cli.Context) error {
 		argv := ctx.Argv().(*setCommandT)
-		argv.Password.PlainPassword = argv.Pw
-		id, new, err := box.Add(&argv.Password)
+		if argv.Update {
+			if argv.ID != "" {
+				return fmt.Errorf("--update conflicts with --id")
+			}
+			id, err := argv.target()
+			if err != nil {
+				return err
+			}
+			argv.Password.ID = id
+		}
+		argv.markSet(ctx)
+		var clipboard []string
+		if argv.Copy {
+			var err error
+			if clipboard, err = clipboardCommand(); err != nil {
+				return err
+			}
+		}
+		generated := ""
+		if ctx.IsSet("--generate") {
+			var err error
+			if generated, err = argv.generate(); err != nil {
+				return err
+			}
+			argv.Pw, argv.Cpw = generated, generated
+		}
+		if err := argv.readPassword(); err != nil {
+			return err
+		}
+		if argv.Pw != "" {
+			argv.Password.PlainPassword = argv.Pw
+			argv.Password.MarkSet(core.FieldPassword)
+		}
+		if err := argv.readFields(); err != nil {
+			return err
+		}
+		if argv.Template != "" {
+			if err := argv.promptFields(); err != nil {
+				return err
+			}
+		}
+		id, new, err := argv.add(ctx)
 		if err != nil {
 			return err
 		}
@@ -235,6 +627,16 @@ var setCommand = &cli.Command{
 		} else {
 			ctx.String("password %s updated\n", ctx.Color().Cyan(id))
 		}
+		argv.warnWeakPassword(ctx)
+		if argv.Reveal {
+			ctx.String("%s\n", generated)
+		}
+		if argv.Copy {
+			if err := writeClipboard(clipboard, generated); err != nil {
+				return fmt.Errorf("copy to clipboard: %v, the generated password is kept in box", err)
+			}
+			ctx.String("generated password copied to clipboard\n")
+		}
 		return nil
 	},
 }
@@ -246,14 +648,26 @@ var setCommand = &cli.Command{
 type removeCommandT struct {
 	cli.Helper2
 	Config
-	All bool `cli:"a,all" usage:"Remove all found passwords" dft:"false"`
+	Category  string `cli:"c,category" usage:"Category of passwords to remove, used with --account"`
+	Account   string `cli:"u,account" usage:"Account of passwords to remove"`
+	All       bool   `cli:"a,all" usage:"Remove all found passwords" dft:"false"`
+	Permanent bool   `cli:"permanent" usage:"Remove passwords permanently instead of moving to trash" dft:"false"`
+	Yes       bool   `cli:"y,yes" usage:"Don't ask for confirmation when removing all passwords" dft:"false"`
+	Force     bool   `cli:"force" usage:"Remove passwords even if they are referenced by other passwords" dft:"false"`
+}
+
+func (argv *removeCommandT) Validate(ctx *cli.Context) error {
+	if argv.Category != "" && argv.Account == "" {
+		return fmt.Errorf("--category must be used with --account")
+	}
+	return nil
 }
 
 var removeCommand = &cli.Command{
 	Name:        "remove",
 	Aliases:     []string{"rm", "del", "delete"},
 	Desc:        "Remove passwords by IDs or (category,account)",
-	Text:        "Usage: onepw rm [IDs...] [OPTIONS]",
+	Text:        "Usage: onepw rm [IDs...] [OPTIONS]\n       onepw rm -c <CATEGORY> -u <ACCOUNT> [OPTIONS]",
 	Argv:        func() interface{} { return new(removeCommandT) },
 	CanSubRoute: true,
 
@@ -265,15 +679,30 @@ var removeCommand = &cli.Command{
 			ids        = ctx.Args()
 		)
 		if len(ids) > 0 {
-			deletedIds, err = box.Remove(ids, argv.All)
+			deletedIds, err = box.Remove(ids, argv.All, argv.Permanent, argv.Force)
+		} else if argv.Account != "" {
+			deletedIds, err = box.RemoveByAccount(argv.Category, argv.Account, argv.All, argv.Permanent, argv.Force)
 		} else if argv.All {
-			deletedIds, err = box.Clear()
+			if !argv.Yes {
+				ok, err := prompt.Ask("Remove all passwords? [y/N] ", false)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return nil
+				}
+			}
+			deletedIds, err = box.Clear(argv.Permanent)
 		}
 
 		if err != nil {
 			return err
 		}
-		ctx.String("deleted passwords:\n")
+		if argv.Permanent {
+			ctx.String("deleted passwords:\n")
+		} else {
+			ctx.String("passwords moved to trash:\n")
+		}
 		ctx.String(ctx.Color().Cyan(strings.Join(deletedIds, "\n")))
 		ctx.String("\n")
 		return nil
@@ -287,19 +716,38 @@ var removeCommand = &cli.Command{
 type listCommandT struct {
 	cli.Helper2
 	Config
-	NoHeader   bool `cli:"no-header" usage:"Don't print header line" dft:"false"`
-	ShowHidden bool `cli:"H,hidden" usage:"Whether to list hidden passwords"`
+	NoHeader   bool   `cli:"no-header" usage:"Don't print header line" dft:"false"`
+	ShowHidden bool   `cli:"H,hidden" usage:"Whether to list hidden passwords"`
+	SortBy     string `cli:"s,sort" usage:"Order of passwords: id,recent,frequent" dft:"id"`
+	Tree       bool   `cli:"t,tree" usage:"List passwords as a tree of folders" dft:"false"`
+	ShowAlias  bool   `cli:"alias" usage:"Show alias column" dft:"false"`
+}
+
+func (argv *listCommandT) Validate(ctx *cli.Context) error {
+	_, err := core.ParseSortBy(argv.SortBy)
+	return err
 }
 
 var listCommand = &cli.Command{
-	Name:    "list",
-	Aliases: []string{"ls"},
-	Desc:    "List all passwords",
-	Argv:    func() interface{} { return new(listCommandT) },
+	Name:        "list",
+	Aliases:     []string{"ls"},
+	Desc:        "List all passwords or passwords in the folder",
+	Text:        "Usage: onepw ls [FOLDER] [OPTIONS]",
+	Argv:        func() interface{} { return new(listCommandT) },
+	CanSubRoute: true,
+	NumArg:      cli.AtMost(1),
 
 	Fn: func(ctx *cli.Context) error {
 		argv := ctx.Argv().(*listCommandT)
-		return box.List(ctx, argv.NoHeader, argv.ShowHidden)
+		folder := ""
+		if ctx.NArg() > 0 {
+			folder = ctx.Args()[0]
+		}
+		if argv.Tree {
+			return box.Tree(ctx, folder, argv.ShowHidden)
+		}
+		sortBy, _ := core.ParseSortBy(argv.SortBy)
+		return box.List(ctx, folder, argv.NoHeader, argv.ShowHidden, argv.ShowAlias, sortBy)
 	},
 }
 
@@ -310,18 +758,29 @@ var listCommand = &cli.Command{
 type findCommandT struct {
 	cli.Helper2
 	Config
-	JustPassword bool `cli:"p,just-password" usage:"Just show password" dft:"false"`
-	JustFirst    bool `cli:"f,just-first" usage:"Just show first result" dft:"false"`
+	JustPassword bool   `cli:"p,just-password" usage:"Just show password" dft:"false"`
+	JustFirst    bool   `cli:"f,just-first" usage:"Just show first result" dft:"false"`
+	SortBy       string `cli:"s,sort" usage:"Order of passwords: id,recent,frequent" dft:"id"`
+	URL          string `cli:"url" usage:"Find passwords which apply to the URL, ranked by match strength"`
+}
+
+func (argv *findCommandT) Validate(ctx *cli.Context) error {
+	_, err := core.ParseSortBy(argv.SortBy)
+	return err
 }
 
 var findCommand = &cli.Command{
 	Name:        "find",
 	Desc:        "Find password by ID,category,account,tag or site and so on",
-	Text:        "Usage: onepw find <WORD>",
+	Text:        "Usage: onepw find <WORD>\n       onepw find @ALIAS\n       onepw find --url <URL>",
 	Argv:        func() interface{} { return new(findCommandT) },
 	CanSubRoute: true,
 
 	OnBefore: func(ctx *cli.Context) error {
+		argv := ctx.Argv().(*findCommandT)
+		if argv.URL != "" && len(ctx.Args()) == 0 {
+			return nil
+		}
 		if len(ctx.Args()) != 1 {
 			ctx.WriteUsage()
 			return cli.ExitError
@@ -331,7 +790,137 @@ var findCommand = &cli.Command{
 
 	Fn: func(ctx *cli.Context) error {
 		argv := ctx.Argv().(*findCommandT)
-		box.Find(ctx, ctx.Args()[0], argv.JustPassword, argv.JustFirst)
+		if argv.URL != "" {
+			return box.FindURL(ctx, argv.URL, argv.JustPassword, argv.JustFirst)
+		}
+		sortBy, _ := core.ParseSortBy(argv.SortBy)
+		return box.Find(ctx, ctx.Args()[0], argv.JustPassword, argv.JustFirst, sortBy)
+	},
+}
+
+//------------------
+// generate command
+//------------------
+
+type generateCommandT struct {
+	cli.Helper2
+	NoMasterConfig
+	Number         int    `cli:"n,number" usage:"Number of generated passwords" dft:"1" name:"N"`
+	Digit          bool   `cli:"d,digit" usage:"Whether the password contains digit" dft:"false"`
+	LowerChar      bool   `cli:"c,lower-char" usage:"Whether the password contains lowercase character" dft:"false"`
+	UpperChar      bool   `cli:"C,upper-char" usage:"Whether the password contains uppercase character" dft:"false"`
+	SpecialChar    bool   `cli:"s,special-char" usage:"Whether the password contains the special character" dft:"false"`
+	SpecialSet     string `cli:"sset,special-set" usage:"Custom special character set"`
+	ExcludeSimilar bool   `cli:"x,exclude-similar" usage:"Exclude look-alike characters, e.g. l and 1, O and 0" dft:"false"`
+	Words          int    `cli:"w,words" usage:"Generate passphrase of N words instead of LEN characters, -d adds a digit" name:"N"`
+	Separator      string `cli:"sep" usage:"Separator of passphrase words" dft:"-"`
+	Capitalize     bool   `cli:"capitalize" usage:"Capitalize passphrase words" dft:"false"`
+	Wordlist       string `cli:"wordlist" usage:"Wordlist file of passphrase, one word per line" name:"FILE"`
+	Pattern        string `cli:"pattern" usage:"Generate password by pattern instead of LEN, e.g. Aaaa-9999-! or A{2}9{6}"`
+}
+
+func (argv *generateCommandT) Validate(ctx *cli.Context) error {
+	if argv.Number <= 0 {
+		return fmt.Errorf("number of generated passwords must be positive")
+	}
+	if argv.Words < 0 {
+		return fmt.Errorf("number of words must be positive")
+	}
+	modes := 0
+	for _, given := range []bool{ctx.NArg() > 0, argv.Words > 0, argv.Pattern != ""} {
+		if given {
+			modes++
+		}
+	}
+	if modes != 1 {
+		return fmt.Errorf("exactly one of LEN, --words and --pattern required")
+	}
+	return nil
+}
+
+// passphrase generates passphrases and reports the entropy
+func (argv *generateCommandT) passphrase(ctx *cli.Context) error {
+	p := core.Passphrase{
+		Words:      argv.Words,
+		Separator:  argv.Separator,
+		Capitalize: argv.Capitalize,
+		Digit:      argv.Digit,
+	}
+	if argv.Wordlist != "" {
+		words, err := core.LoadWordlist(argv.Wordlist)
+		if err != nil {
+			return err
+		}
+		p.Wordlist = words
+	}
+	for i := 0; i < argv.Number; i++ {
+		passphrase, err := p.Generate()
+		if err != nil {
+			return err
+		}
+		ctx.String("%s\n", passphrase)
+	}
+	ctx.String("entropy: %.1f bits\n", p.Entropy())
+	return nil
+}
+
+// generator returns the generator, digit, lowercase and uppercase characters are
+// used if no class specified
+func (argv *generateCommandT) generator() core.Generator {
+	return core.Generator{
+		Digit:          argv.Digit,
+		Lower:          argv.LowerChar,
+		Upper:          argv.UpperChar,
+		Special:        argv.SpecialChar || argv.SpecialSet != "",
+		SpecialSet:     argv.SpecialSet,
+		ExcludeSimilar: argv.ExcludeSimilar,
+	}
+}
+
+var generateCommand = &cli.Command{
+	Name:    "generate",
+	Aliases: []string{"gen"},
+	Desc:    "Generate password",
+	Text: `Usage: onepw gen [OPTIONS] LEN
+       onepw gen --pattern PATTERN
+       onepw gen --words N [--sep SEP] [--capitalize] [-d] [--wordlist FILE]
+
+Placeholders a, A, 9, ! and * of PATTERN are replaced by a random lowercase,
+uppercase, digit, special or any character, {N} repeats the previous placeholder
+or character N times, \ escapes the next character, others are literal.`,
+	Argv:        func() interface{} { return new(generateCommandT) },
+	CanSubRoute: true,
+	NumArg:      cli.AtMost(1),
+
+	Fn: func(ctx *cli.Context) error {
+		argv := ctx.Argv().(*generateCommandT)
+		if argv.Words > 0 {
+			return argv.passphrase(ctx)
+		}
+		length := 0
+		if argv.Pattern == "" {
+			var err error
+			length, err = strconv.Atoi(ctx.Args()[0])
+			if err != nil || length <= 0 {
+				return fmt.Errorf("invalid length %s", ctx.Args()[0])
+			}
+		}
+		gen := argv.generator()
+		for i := 0; i < argv.Number; i++ {
+			var (
+				password string
+				err      error
+			)
+			if argv.Pattern != "" {
+				password, err = gen.GeneratePattern(argv.Pattern)
+			} else {
+				password, err = gen.Generate(length)
+			}
+			if err != nil {
+				return err
+			}
+			ctx.String("%s\n", password)
+		}
 		return nil
 	},
 }
@@ -374,7 +963,7 @@ var infoCommand = &cli.Command{
 	Name:        "show",
 	Aliases:     []string{"info"},
 	Desc:        "Show low-level information of password",
-	Text:        "Usage: onepw show <IDs...>",
+	Text:        "Usage: onepw show <IDs or @ALIASes...>",
 	Argv:        func() interface{} { return new(infoCommandT) },
 	CanSubRoute: true,
 	NumArg:      cli.AtLeast(1),
@@ -384,3 +973,1110 @@ var infoCommand = &cli.Command{
 		return box.Inspect(ctx, ctx.Args(), argv.All)
 	},
 }
+
+//--------------
+// edit command
+//--------------
+
+type editCommandT struct {
+	cli.Helper2
+	Config
+}
+
+var editCommand = &cli.Command{
+	Name:        "edit",
+	Desc:        "Edit a password as TOML in $EDITOR",
+	Text:        "Usage: onepw edit <ID>",
+	Argv:        func() interface{} { return new(editCommandT) },
+	CanSubRoute: true,
+	NumArg:      cli.ExactN(1),
+
+	Fn: func(ctx *cli.Context) error {
+		pw, err := box.Get(ctx.Args()[0])
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := core.WriteEntry(&buf, pw); err != nil {
+			return err
+		}
+		var (
+			data    = buf.Bytes()
+			lastErr error
+		)
+		for {
+			edited, err := editInTempFile(data)
+			if err != nil {
+				return err
+			}
+			// quit editor without changes
+			if bytes.Equal(edited, data) {
+				if lastErr != nil {
+					return lastErr
+				}
+				ctx.String("password %s not changed\n", ctx.Color().Cyan(pw.ID))
+				return nil
+			}
+			data = edited
+			id, err := addEntry(pw.ID, data)
+			if err == nil {
+				ctx.String("password %s updated\n", ctx.Color().Cyan(id))
+				return nil
+			}
+			// keep the edits and reopen editor with the error
+			lastErr = err
+			ctx.String("%s\n", ctx.Color().Red(err.Error()))
+			data = append([]byte("# ERROR: "+strings.Replace(err.Error(), "\n", " ", -1)+"\n"),
+				bytes.TrimPrefix(data, errorLine(data))...)
+		}
+	},
+}
+
+// errorLine returns the leading error line added by edit command
+func errorLine(data []byte) []byte {
+	if !bytes.HasPrefix(data, []byte("# ERROR: ")) {
+		return nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return data[:i+1]
+	}
+	return data
+}
+
+// addEntry parses the edited entry and writes it back to box
+func addEntry(id string, data []byte) (string, error) {
+	pw, err := core.ParseEntry(data)
+	if err != nil {
+		return "", err
+	}
+	pw.ID = id
+	id, _, err = box.Add(pw)
+	return id, err
+}
+
+// editInTempFile opens data in editor, the temporary file is created in a
+// private directory and is overwritten and removed after editing
+func editInTempFile(data []byte) ([]byte, error) {
+	parent := ""
+	if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+		parent = "/dev/shm"
+	}
+	dir, err := ioutil.TempDir(parent, "onepw-")
+	if err != nil {
+		dir, err = ioutil.TempDir("", "onepw-")
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Chmod(dir, 0700); err != nil {
+		return nil, err
+	}
+	filename := filepath.Join(dir, "entry.toml")
+	defer wipeFile(filename)
+	if err := ioutil.WriteFile(filename, data, 0600); err != nil {
+		return nil, err
+	}
+	// blank $VISUAL or $EDITOR is ignored
+	args := strings.Fields(os.Getenv("VISUAL"))
+	if len(args) == 0 {
+		args = strings.Fields(os.Getenv("EDITOR"))
+	}
+	if len(args) == 0 {
+		args = []string{"vi"}
+	}
+	cmd := exec.Command(args[0], append(args[1:], filename)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("editor %s: %v", strings.Join(args, " "), err)
+	}
+	return ioutil.ReadFile(filename)
+}
+
+// wipeFile overwrites the file with zeros and removes it
+func wipeFile(filename string) {
+	if info, err := os.Stat(filename); err == nil {
+		if file, err := os.OpenFile(filename, os.O_WRONLY, 0); err == nil {
+			file.Write(make([]byte, info.Size()))
+			file.Sync()
+			file.Close()
+		}
+	}
+	os.Remove(filename)
+}
+
+//--------------
+// move command
+//--------------
+
+type moveCommandT struct {
+	cli.Helper2
+	Config
+	Category string `cli:"*c,category" usage:"New category(folder) of passwords"`
+	Yes      bool   `cli:"y,yes" usage:"Don't ask for confirmation" dft:"false"`
+}
+
+// confirmQuery prints passwords found by word and asks whether to apply the action
+// to them, yes skips both
+func confirmQuery(ctx *cli.Context, word, action string, yes bool) (bool, error) {
+	if yes {
+		return true, nil
+	}
+	n, err := box.Query(ctx, word)
+	if err != nil {
+		return false, err
+	}
+	return prompt.Ask(fmt.Sprintf("%s %d passwords? [y/N] ", action, n), false)
+}
+
+var moveCommand = &cli.Command{
+	Name:        "mv",
+	Aliases:     []string{"move"},
+	Desc:        "Move passwords found by WORD into the category",
+	Text:        "Usage: onepw mv <WORD> --category <CATEGORY> [-y]",
+	Argv:        func() interface{} { return new(moveCommandT) },
+	CanSubRoute: true,
+	NumArg:      cli.ExactN(1),
+
+	Fn: func(ctx *cli.Context) error {
+		argv := ctx.Argv().(*moveCommandT)
+		if ok, err := confirmQuery(ctx, ctx.Args()[0], "Move", argv.Yes); err != nil || !ok {
+			return err
+		}
+		ids, err := box.Move(ctx.Args()[0], argv.Category)
+		if err != nil {
+			return err
+		}
+		ctx.String("passwords moved to %s:\n", ctx.Color().Bold(core.CleanFolder(argv.Category)))
+		ctx.String(ctx.Color().Cyan(strings.Join(ids, "\n")))
+		ctx.String("\n")
+		return nil
+	},
+}
+
+//-------------
+// tag command
+//-------------
+
+type tagCommandT struct {
+	cli.Helper2
+	Config
+}
+
+var tagCommand = &cli.Command{
+	Name: "tag",
+	Desc: "Edit tags of passwords",
+	Text: "Usage: onepw tag <add|rm|list> [OPTIONS]",
+	Argv: func() interface{} { return new(tagCommandT) },
+
+	Fn: func(ctx *cli.Context) error {
+		ctx.WriteUsage()
+		return nil
+	},
+}
+
+type tagEditCommandT struct {
+	cli.Helper2
+	Config
+	Yes bool `cli:"y,yes" usage:"Don't ask for confirmation" dft:"false"`
+}
+
+var tagAddCommand = &cli.Command{
+	Name:        "add",
+	Desc:        "Add tags to passwords found by WORD",
+	Text:        "Usage: onepw tag add <WORD> <TAGs...> [-y]",
+	Argv:        func() interface{} { return new(tagEditCommandT) },
+	CanSubRoute: true,
+	NumArg:      cli.AtLeast(2),
+
+	Fn: func(ctx *cli.Context) error {
+		argv := ctx.Argv().(*tagEditCommandT)
+		if ok, err := confirmQuery(ctx, ctx.Args()[0], "Add tags to", argv.Yes); err != nil || !ok {
+			return err
+		}
+		ids, err := box.AddTags(ctx.Args()[0], ctx.Args()[1:])
+		if err != nil {
+			return err
+		}
+		ctx.String("%d passwords changed\n", len(ids))
+		return nil
+	},
+}
+
+var tagRemoveCommand = &cli.Command{
+	Name:        "remove",
+	Aliases:     []string{"rm"},
+	Desc:        "Remove tags from passwords found by WORD",
+	Text:        "Usage: onepw tag rm <WORD> <TAGs...> [-y]",
+	Argv:        func() interface{} { return new(tagEditCommandT) },
+	CanSubRoute: true,
+	NumArg:      cli.AtLeast(2),
+
+	Fn: func(ctx *cli.Context) error {
+		argv := ctx.Argv().(*tagEditCommandT)
+		if ok, err := confirmQuery(ctx, ctx.Args()[0], "Remove tags from", argv.Yes); err != nil || !ok {
+			return err
+		}
+		ids, err := box.RemoveTags(ctx.Args()[0], ctx.Args()[1:])
+		if err != nil {
+			return err
+		}
+		ctx.String("%d passwords changed\n", len(ids))
+		return nil
+	},
+}
+
+var tagListCommand = &cli.Command{
+	Name:    "list",
+	Aliases: []string{"ls"},
+	Desc:    "List all tags with number of passwords",
+	Argv:    func() interface{} { return new(tagCommandT) },
+
+	Fn: func(ctx *cli.Context) error {
+		return box.ListTags(ctx)
+	},
+}
+
+//----------------
+// folder command
+//----------------
+
+type folderCommandT struct {
+	cli.Helper2
+	Config
+}
+
+var folderCommand = &cli.Command{
+	Name: "folder",
+	Desc: "Manage folders, category of password is a folder path like work/aws/prod",
+	Text: "Usage: onepw folder <mv|rename|rm> [OPTIONS]",
+	Argv: func() interface{} { return new(folderCommandT) },
+
+	Fn: func(ctx *cli.Context) error {
+		ctx.WriteUsage()
+		return nil
+	},
+}
+
+var folderMoveCommand = &cli.Command{
+	Name:        "move",
+	Aliases:     []string{"mv"},
+	Desc:        "Move folder and sub-folders into another folder, e.g. moving work/aws into cloud results in cloud/aws",
+	Text:        "Usage: onepw folder mv <SRC> <DST>",
+	Argv:        func() interface{} { return new(folderCommandT) },
+	CanSubRoute: true,
+	NumArg:      cli.ExactN(2),
+
+	Fn: func(ctx *cli.Context) error {
+		ids, err := box.MoveFolder(ctx.Args()[0], ctx.Args()[1])
+		if err != nil {
+			return err
+		}
+		ctx.String("%d passwords moved\n", len(ids))
+		return nil
+	},
+}
+
+var folderRenameCommand = &cli.Command{
+	Name:        "rename",
+	Desc:        "Rename folder",
+	Text:        "Usage: onepw folder rename <FOLDER> <NEW_NAME>",
+	Argv:        func() interface{} { return new(folderCommandT) },
+	CanSubRoute: true,
+	NumArg:      cli.ExactN(2),
+
+	Fn: func(ctx *cli.Context) error {
+		ids, err := box.RenameFolder(ctx.Args()[0], ctx.Args()[1])
+		if err != nil {
+			return err
+		}
+		ctx.String("%d passwords moved\n", len(ids))
+		return nil
+	},
+}
+
+type folderRemoveCommandT struct {
+	cli.Helper2
+	Config
+	Permanent bool `cli:"permanent" usage:"Remove passwords permanently instead of moving to trash" dft:"false"`
+	Force     bool `cli:"force" usage:"Remove passwords even if they are referenced by passwords out of the folder" dft:"false"`
+}
+
+var folderRemoveCommand = &cli.Command{
+	Name:        "remove",
+	Aliases:     []string{"rm"},
+	Desc:        "Remove all passwords in folder and sub-folders",
+	Text:        "Usage: onepw folder rm <FOLDER> [OPTIONS]",
+	Argv:        func() interface{} { return new(folderRemoveCommandT) },
+	CanSubRoute: true,
+	NumArg:      cli.ExactN(1),
+
+	Fn: func(ctx *cli.Context) error {
+		argv := ctx.Argv().(*folderRemoveCommandT)
+		ids, err := box.RemoveFolder(ctx.Args()[0], argv.Permanent, argv.Force)
+		if err != nil {
+			return err
+		}
+		if argv.Permanent {
+			ctx.String("deleted passwords:\n")
+		} else {
+			ctx.String("passwords moved to trash:\n")
+		}
+		ctx.String(ctx.Color().Cyan(strings.Join(ids, "\n")))
+		ctx.String("\n")
+		return nil
+	},
+}
+
+//------------------
+// favorite command
+//------------------
+
+type favoriteCommandT struct {
+	cli.Helper2
+	Config
+	Off bool `cli:"off" usage:"Unmark passwords as favorite" dft:"false"`
+}
+
+var favoriteCommand = &cli.Command{
+	Name:        "fav",
+	Aliases:     []string{"favorite"},
+	Desc:        "Mark passwords as favorite, favorites come first when sorted by recent or frequent",
+	Text:        "Usage: onepw fav <IDs...> [OPTIONS]",
+	Argv:        func() interface{} { return new(favoriteCommandT) },
+	CanSubRoute: true,
+	NumArg:      cli.AtLeast(1),
+
+	Fn: func(ctx *cli.Context) error {
+		argv := ctx.Argv().(*favoriteCommandT)
+		ids, err := box.SetFavorite(ctx.Args(), !argv.Off)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if argv.Off {
+				ctx.String("password %s unmarked as favorite\n", ctx.Color().Cyan(id))
+			} else {
+				ctx.String("password %s marked as favorite\n", ctx.Color().Cyan(id))
+			}
+		}
+		return nil
+	},
+}
+
+//------------------
+// expiring command
+//------------------
+
+type expiringCommandT struct {
+	cli.Helper2
+	Config
+	Within string `cli:"w,within" usage:"Report passwords which expire within the duration, e.g. 30d, 2w, 12h" dft:"30d"`
+}
+
+func (argv *expiringCommandT) Validate(ctx *cli.Context) error {
+	_, err := core.ParseDuration(argv.Within)
+	return err
+}
+
+var expiringCommand = &cli.Command{
+	Name: "expiring",
+	Desc: "List expired or expiring passwords, exit with non-zero code if any found",
+	Argv: func() interface{} { return new(expiringCommandT) },
+
+	Fn: func(ctx *cli.Context) error {
+		argv := ctx.Argv().(*expiringCommandT)
+		within, _ := core.ParseDuration(argv.Within)
+		n, err := box.Expiring(ctx, within)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return fmt.Errorf(ctx.Color().Red("%d passwords expired or expiring within %s"), n, argv.Within)
+		}
+		return nil
+	},
+}
+
+//---------------
+// audit command
+//---------------
+
+type auditCommandT struct {
+	cli.Helper2
+	Config
+	Days        int  `cli:"days" usage:"Report passwords not updated within the days, 0 disables the check" dft:"180"`
+	MinScore    int  `cli:"min-score" usage:"Report passwords whose strength score(0-4) is less than it" dft:"3"`
+	MaxDistance int  `cli:"max-distance" usage:"Report passwords whose edit distance to the master password is at most it" dft:"2"`
+	JSON        bool `cli:"json" usage:"Output findings as JSON" dft:"false"`
+}
+
+func (argv *auditCommandT) Validate(ctx *cli.Context) error {
+	if argv.Days < 0 || argv.MaxDistance < 0 {
+		return fmt.Errorf("--days and --max-distance must not be negative")
+	}
+	if argv.MinScore < 0 || argv.MinScore > 4 {
+		return fmt.Errorf("min score must be in [0,4]")
+	}
+	return nil
+}
+
+var auditCommand = &cli.Command{
+	Name: "audit",
+	Desc: "Report reused, weak, old passwords and passwords close to the master password or with broken references, exit with non-zero code if any high-severity finding",
+	Argv: func() interface{} { return new(auditCommandT) },
+
+	Fn: func(ctx *cli.Context) error {
+		argv := ctx.Argv().(*auditCommandT)
+		findings, err := box.Audit(core.AuditOptions{
+			MaxAge:      time.Duration(argv.Days) * 24 * time.Hour,
+			MinScore:    argv.MinScore,
+			MaxDistance: argv.MaxDistance,
+		})
+		if err != nil {
+			return err
+		}
+		if err := core.WriteFindings(ctx, findings, argv.JSON); err != nil {
+			return err
+		}
+		high := 0
+		for _, f := range findings {
+			if f.Severity == core.SeverityHigh {
+				high++
+			}
+		}
+		if high > 0 {
+			return fmt.Errorf(ctx.Color().Red("%d high-severity findings"), high)
+		}
+		if len(findings) == 0 && !argv.JSON {
+			ctx.String("no findings\n")
+		}
+		return nil
+	},
+}
+
+//----------------
+// breach command
+//----------------
+
+type breachCommandT struct {
+	cli.Helper2
+	Config
+	DB string `cli:"*db" usage:"Directory of Pwned Passwords range files or a file of HASH:COUNT lines sorted by hash"`
+}
+
+var breachCommand = &cli.Command{
+	Name: "breach",
+	Desc: "Check passwords against an offline Pwned Passwords database, exit with non-zero code if any breached",
+	Argv: func() interface{} { return new(breachCommandT) },
+
+	Fn: func(ctx *cli.Context) error {
+		argv := ctx.Argv().(*breachCommandT)
+		db, err := core.OpenBreachDB(argv.DB)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		n, err := box.Breach(ctx, db)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return fmt.Errorf(ctx.Color().Red("%d breached passwords"), n)
+		}
+		ctx.String("no breached passwords\n")
+		return nil
+	},
+}
+
+//----------------
+// doctor command
+//----------------
+
+type doctorCommandT struct {
+	cli.Helper2
+	NoMasterConfig
+}
+
+var doctorCommand = &cli.Command{
+	Name: "doctor",
+	Desc: "Check environment, file permission, format and backups of the box without the master password, exit with non-zero code if any high-severity problem",
+	Argv: func() interface{} { return new(doctorCommandT) },
+
+	Fn: func(ctx *cli.Context) error {
+		argv := ctx.Argv().(*doctorCommandT)
+		diagnoses, err := box.Doctor()
+		if err != nil {
+			return err
+		}
+		fileDiagnoses, err := core.DiagnoseFile(argv.Filename())
+		if err != nil {
+			return err
+		}
+		diagnoses = append(diagnoses, fileDiagnoses...)
+		if os.Getenv("ONEPW_MASTER") != "" {
+			diagnoses = append(diagnoses, core.Diagnosis{
+				Check:       core.CheckEnvMaster,
+				Severity:    core.SeverityMedium,
+				Detail:      "master password set by ONEPW_MASTER is visible to child processes",
+				Remediation: "unset ONEPW_MASTER",
+			})
+		}
+		core.SortDiagnoses(diagnoses)
+		core.WriteDiagnoses(ctx, diagnoses)
+		high := 0
+		for _, d := range diagnoses {
+			if d.Severity == core.SeverityHigh {
+				high++
+			}
+		}
+		if high > 0 {
+			return fmt.Errorf(ctx.Color().Red("%d high-severity problems"), high)
+		}
+		if len(diagnoses) == 0 {
+			ctx.String("no problems\n")
+		}
+		return nil
+	},
+}
+
+//---------------
+// trash command
+//---------------
+
+type trashCommandT struct {
+	cli.Helper2
+	Config
+}
+
+var trashCommand = &cli.Command{
+	Name: "trash",
+	Desc: "Manage removed passwords",
+	Text: "Usage: onepw trash <list|restore|empty|config> [OPTIONS]",
+	Argv: func() interface{} { return new(trashCommandT) },
+
+	Fn: func(ctx *cli.Context) error {
+		ctx.WriteUsage()
+		return nil
+	},
+}
+
+var trashListCommand = &cli.Command{
+	Name:    "list",
+	Aliases: []string{"ls"},
+	Desc:    "List passwords in trash",
+	Argv:    func() interface{} { return new(trashCommandT) },
+
+	Fn: func(ctx *cli.Context) error {
+		return box.ListTrash(ctx)
+	},
+}
+
+type trashRestoreCommandT struct {
+	cli.Helper2
+	Config
+	All bool `cli:"a,all" usage:"Restore all found passwords" dft:"false"`
+}
+
+var trashRestoreCommand = &cli.Command{
+	Name:        "restore",
+	Desc:        "Restore passwords from trash by IDs",
+	Text:        "Usage: onepw trash restore <IDs...> [OPTIONS]",
+	Argv:        func() interface{} { return new(trashRestoreCommandT) },
+	CanSubRoute: true,
+	NumArg:      cli.AtLeast(1),
+
+	Fn: func(ctx *cli.Context) error {
+		argv := ctx.Argv().(*trashRestoreCommandT)
+		ids, err := box.Restore(ctx.Args(), argv.All)
+		if err != nil {
+			return err
+		}
+		ctx.String("restored passwords:\n")
+		ctx.String(ctx.Color().Cyan(strings.Join(ids, "\n")))
+		ctx.String("\n")
+		return nil
+	},
+}
+
+type trashEmptyCommandT struct {
+	cli.Helper2
+	Config
+	OlderThan string `cli:"older-than" usage:"Only remove passwords which stay in trash longer than the duration, e.g. 30d" dft:"0s"`
+}
+
+func (argv *trashEmptyCommandT) Validate(ctx *cli.Context) error {
+	_, err := core.ParseDuration(argv.OlderThan)
+	return err
+}
+
+var trashEmptyCommand = &cli.Command{
+	Name: "empty",
+	Desc: "Remove passwords in trash permanently",
+	Argv: func() interface{} { return new(trashEmptyCommandT) },
+
+	Fn: func(ctx *cli.Context) error {
+		argv := ctx.Argv().(*trashEmptyCommandT)
+		olderThan, _ := core.ParseDuration(argv.OlderThan)
+		ids, err := box.EmptyTrash(olderThan)
+		if err != nil {
+			return err
+		}
+		ctx.String("deleted passwords:\n")
+		ctx.String(ctx.Color().Cyan(strings.Join(ids, "\n")))
+		ctx.String("\n")
+		return nil
+	},
+}
+
+type trashConfigCommandT struct {
+	cli.Helper2
+	Config
+	MaxAge string `cli:"max-age" usage:"Purge passwords which stay in trash longer than the duration automatically, e.g. 30d, 0 means never"`
+}
+
+func (argv *trashConfigCommandT) Validate(ctx *cli.Context) error {
+	if argv.MaxAge == "" || argv.MaxAge == "0" {
+		return nil
+	}
+	_, err := core.ParseDuration(argv.MaxAge)
+	return err
+}
+
+var trashConfigCommand = &cli.Command{
+	Name: "config",
+	Desc: "Show or change trash configuration",
+	Argv: func() interface{} { return new(trashConfigCommandT) },
+
+	Fn: func(ctx *cli.Context) error {
+		argv := ctx.Argv().(*trashConfigCommandT)
+		if ctx.IsSet("--max-age") {
+			var maxAge time.Duration
+			if argv.MaxAge != "0" {
+				maxAge, _ = core.ParseDuration(argv.MaxAge)
+			}
+			if err := box.SetTrashMaxAge(maxAge); err != nil {
+				return err
+			}
+		}
+		if maxAge := box.TrashMaxAge(); maxAge > 0 {
+			ctx.String("max-age: %v\n", maxAge)
+		} else {
+			ctx.String("max-age: never\n")
+		}
+		return nil
+	},
+}
+
+//------------------
+// template command
+//------------------
+
+type templateCommandT struct {
+	cli.Helper2
+	Config
+}
+
+var templateCommand = &cli.Command{
+	Name:    "template",
+	Aliases: []string{"tpl"},
+	Desc:    "Manage user-defined entry templates",
+	Text:    "Usage: onepw template <list|import|rm> [OPTIONS]",
+	Argv:    func() interface{} { return new(templateCommandT) },
+
+	Fn: func(ctx *cli.Context) error {
+		ctx.WriteUsage()
+		return nil
+	},
+}
+
+var templateListCommand = &cli.Command{
+	Name:    "list",
+	Aliases: []string{"ls"},
+	Desc:    "List builtin kinds and templates, required field marked by * and secret field marked by !",
+	Argv:    func() interface{} { return new(templateCommandT) },
+
+	Fn: func(ctx *cli.Context) error {
+		box.ListKinds(ctx)
+		return nil
+	},
+}
+
+var templateImportCommand = &cli.Command{
+	Name: "import",
+	Desc: "Import templates from JSON file, template which has same name will be replaced",
+	Text: `Usage: onepw template import <FILE>
+
+FILE contains an array of templates, e.g.
+
+    [{
+        "Name": "aws-iam",
+        "Desc": "AWS IAM user",
+        "Fields": [
+            {"Name": "account", "Required": true},
+            {"Name": "access_key_id", "Type": "text", "Required": true},
+            {"Name": "secret_access_key", "Secret": true, "Required": true}
+        ]
+    }]`,
+	Argv:        func() interface{} { return new(templateCommandT) },
+	CanSubRoute: true,
+	NumArg:      cli.ExactN(1),
+
+	Fn: func(ctx *cli.Context) error {
+		data, err := ioutil.ReadFile(ctx.Args()[0])
+		if err != nil {
+			return err
+		}
+		templates, err := core.ParseTemplates(data)
+		if err != nil {
+			return err
+		}
+		if err := box.AddTemplates(templates); err != nil {
+			return err
+		}
+		for _, tpl := range templates {
+			ctx.String("template %s imported\n", ctx.Color().Cyan(tpl.Name))
+		}
+		return nil
+	},
+}
+
+var templateRemoveCommand = &cli.Command{
+	Name:        "remove",
+	Aliases:     []string{"rm"},
+	Desc:        "Remove template which is not used by any password",
+	Text:        "Usage: onepw template rm <NAME>",
+	Argv:        func() interface{} { return new(templateCommandT) },
+	CanSubRoute: true,
+	NumArg:      cli.ExactN(1),
+
+	Fn: func(ctx *cli.Context) error {
+		name := ctx.Args()[0]
+		if err := box.RemoveTemplate(name); err != nil {
+			return err
+		}
+		ctx.String("template %s removed\n", ctx.Color().Cyan(name))
+		return nil
+	},
+}
+
+//----------------
+// dedupe command
+//----------------
+
+type dedupeCommandT struct {
+	cli.Helper2
+	Config
+	Merge bool `cli:"m,merge" usage:"Merge each group into the most recently updated password" dft:"false"`
+	Yes   bool `cli:"y,yes" usage:"Don't ask for confirmation when merging" dft:"false"`
+}
+
+var dedupeCommand = &cli.Command{
+	Name: "dedupe",
+	Desc: "List groups of duplicated passwords(same category and account, or same site and account) and merge them",
+	Text: `Usage: onepw dedupe [OPTIONS]
+
+Merging keeps the first password of each group, passwords of others are kept
+as history of it, references to others are redirected to it and others are
+moved to trash.`,
+	Argv: func() interface{} { return new(dedupeCommandT) },
+
+	Fn: func(ctx *cli.Context) error {
+		argv := ctx.Argv().(*dedupeCommandT)
+		n, err := box.ListDuplicates(ctx)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			ctx.String("no duplicated passwords\n")
+			return nil
+		}
+		if !argv.Merge {
+			return nil
+		}
+		if !argv.Yes {
+			ok, err := prompt.Ask(fmt.Sprintf("Merge %d groups? [y/N] ", n), false)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+		}
+		merged, removed, err := box.MergeDuplicates()
+		if err != nil {
+			return err
+		}
+		ctx.String("merged passwords:\n%s\n", ctx.Color().Cyan(strings.Join(merged, "\n")))
+		ctx.String("passwords moved to trash:\n%s\n", ctx.Color().Cyan(strings.Join(removed, "\n")))
+		return nil
+	},
+}
+
+//----------------
+// rotate command
+//----------------
+
+type rotateCommandT struct {
+	cli.Helper2
+	Config
+	OlderThan string `cli:"older-than" usage:"Rotate all passwords not updated within the duration, e.g. 180d, 26w" name:"DURATION"`
+	Copy      bool   `cli:"copy" usage:"Copy the new password to clipboard instead of printing it" dft:"false"`
+	Yes       bool   `cli:"y,yes" usage:"Don't ask for confirmation when rotating passwords older than DURATION" dft:"false"`
+}
+
+func (argv *rotateCommandT) Validate(ctx *cli.Context) error {
+	if argv.OlderThan == "" {
+		if ctx.NArg() != 1 {
+			return fmt.Errorf("either WORD or --older-than required")
+		}
+		return nil
+	}
+	if ctx.NArg() != 0 {
+		return fmt.Errorf("WORD conflicts with --older-than")
+	}
+	if argv.Copy {
+		return fmt.Errorf("--copy conflicts with --older-than")
+	}
+	_, err := core.ParseDuration(argv.OlderThan)
+	return err
+}
+
+var rotateCommand = &cli.Command{
+	Name: "rotate",
+	Desc: "Replace passwords with new ones generated by their policies",
+	Text: `Usage: onepw rotate [OPTIONS] WORD
+       onepw rotate [OPTIONS] --older-than DURATION
+
+The new password satisfies the policy set by onepw set --policy, passwords
+without policy use length=20,classes=dcCs. Old passwords are kept as history.
+WORD finds the only password by id, @alias or like onepw find.`,
+	Argv:        func() interface{} { return new(rotateCommandT) },
+	CanSubRoute: true,
+	NumArg:      cli.AtMost(1),
+
+	Fn: func(ctx *cli.Context) error {
+		argv := ctx.Argv().(*rotateCommandT)
+		if argv.OlderThan == "" {
+			var clipboard []string
+			if argv.Copy {
+				var err error
+				if clipboard, err = clipboardCommand(); err != nil {
+					return err
+				}
+			}
+			id, err := box.FindOne(ctx.Args()[0])
+			if err != nil {
+				return err
+			}
+			generated, err := box.Rotate([]string{id})
+			if err != nil {
+				return err
+			}
+			ctx.String("password %s rotated\n", ctx.Color().Cyan(id))
+			if argv.Copy {
+				if err := writeClipboard(clipboard, generated[id]); err != nil {
+					return fmt.Errorf("copy to clipboard: %v, the new password is kept in box", err)
+				}
+				ctx.String("new password copied to clipboard\n")
+				return nil
+			}
+			ctx.String("%s\n", generated[id])
+			return nil
+		}
+		d, _ := core.ParseDuration(argv.OlderThan)
+		ids, err := box.OlderThan(ctx, d)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			ctx.String("no passwords older than %s\n", argv.OlderThan)
+			return nil
+		}
+		if !argv.Yes {
+			ok, err := prompt.Ask(fmt.Sprintf("Rotate %d passwords? [y/N] ", len(ids)), false)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+		}
+		generated, err := box.Rotate(ids)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			ctx.String("%s %s\n", ctx.Color().Cyan(id), generated[id])
+		}
+		return nil
+	},
+}
+
+//----------------
+// derive command
+//----------------
+
+type deriveCommandT struct {
+	cli.Helper2
+	Config
+	Site     string `cli:"*site" usage:"Site of password, e.g. example.com"`
+	Account  string `cli:"*u,account" usage:"Login of password"`
+	Counter  int    `cli:"counter" usage:"Counter of password, increase it to change the password" dft:"1"`
+	Length   int    `cli:"length" usage:"Length of password(5-35)" dft:"16"`
+	Classes  string `cli:"classes" usage:"Character classes, composed of d(digit),c(lowercase),C(uppercase),s(special)" dft:"dcCs"`
+	Save     bool   `cli:"save" usage:"Save as a derived password entry, whose password is derived on reading instead of stored" dft:"false"`
+	Category string `cli:"c,category" usage:"Category of saved password"`
+	Copy     bool   `cli:"copy" usage:"Copy the password to clipboard instead of printing it" dft:"false"`
+}
+
+func (argv *deriveCommandT) derivation() core.Derivation {
+	return core.Derivation{Counter: argv.Counter, Length: argv.Length, Classes: argv.Classes}
+}
+
+func (argv *deriveCommandT) Validate(ctx *cli.Context) error {
+	if argv.Category != "" && !argv.Save {
+		return fmt.Errorf("--category is used with --save")
+	}
+	return argv.derivation().Validate()
+}
+
+var deriveCommand = &cli.Command{
+	Name: "derive",
+	Desc: "Derive a password from the master password, site, account and counter",
+	Text: `Usage: onepw derive --site SITE -u ACCOUNT [OPTIONS]
+
+Derived passwords are compatible with LessPass, nothing needs to be stored.
+Saved derived passwords are derived by onepw find and show, and changed by
+onepw rotate which increases the counter.
+
+NOTE: derived passwords change with the master password.`,
+	Argv: func() interface{} { return new(deriveCommandT) },
+
+	Fn: func(ctx *cli.Context) error {
+		argv := ctx.Argv().(*deriveCommandT)
+		var clipboard []string
+		if argv.Copy {
+			var err error
+			if clipboard, err = clipboardCommand(); err != nil {
+				return err
+			}
+		}
+		d := argv.derivation()
+		password, err := box.Derive(argv.Site, argv.Account, d)
+		if err != nil {
+			return err
+		}
+		if argv.Save {
+			pw := core.NewPassword(argv.Category, argv.Account, "", argv.Site)
+			pw.Derived = &d
+			id, _, err := box.Add(pw)
+			if err != nil {
+				return err
+			}
+			ctx.String("password %s added\n", ctx.Color().Cyan(id))
+		}
+		if argv.Copy {
+			if err := writeClipboard(clipboard, password); err != nil {
+				return err
+			}
+			ctx.String("password copied to clipboard\n")
+			return nil
+		}
+		ctx.String("%s\n", password)
+		return nil
+	},
+}
+
+// clipboardCommand returns the first available command which copies stdin to
+// system clipboard
+func clipboardCommand() ([]string, error) {
+	for _, args := range [][]string{
+		{"pbcopy"},
+		{"wl-copy"},
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+	} {
+		if _, err := exec.LookPath(args[0]); err == nil {
+			return args, nil
+		}
+	}
+	return nil, fmt.Errorf("no clipboard tool found, install one of pbcopy, wl-copy, xclip and xsel")
+}
+
+// writeClipboard writes text to system clipboard by command returned by clipboardCommand
+func writeClipboard(args []string, text string) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
+
+//-------------
+// box command
+//-------------
+
+type boxCommandT struct {
+	cli.Helper2
+	Config
+}
+
+var boxCommand = &cli.Command{
+	Name: "box",
+	Desc: "Show or configure metadata of password box",
+	Text: "Usage: onepw box <info|config> [OPTIONS]",
+	Argv: func() interface{} { return new(boxCommandT) },
+
+	Fn: func(ctx *cli.Context) error {
+		ctx.WriteUsage()
+		return nil
+	},
+}
+
+type boxInfoCommandT struct {
+	cli.Helper2
+	NoMasterConfig
+}
+
+var boxInfoCommand = &cli.Command{
+	Name: "info",
+	Desc: "Show metadata, format version and number of passwords without the master password",
+	Text: "Usage: onepw box info",
+	Argv: func() interface{} { return new(boxInfoCommandT) },
+
+	Fn: func(ctx *cli.Context) error {
+		return box.Info(ctx)
+	},
+}
+
+type boxConfigCommandT struct {
+	cli.Helper2
+	Config
+	Name        string `cli:"name" usage:"Name of the box"`
+	Description string `cli:"desc,description" usage:"Description of the box"`
+	Hint        string `cli:"hint" usage:"Hint of the master password, stored as plaintext"`
+}
+
+var boxConfigCommand = &cli.Command{
+	Name: "config",
+	Desc: "Set name, description or master password hint of the box",
+	Text: "Usage: onepw box config [--name NAME] [--desc DESCRIPTION] [--hint HINT]",
+	Argv: func() interface{} { return new(boxConfigCommandT) },
+
+	Fn: func(ctx *cli.Context) error {
+		argv := ctx.Argv().(*boxConfigCommandT)
+		meta := box.Meta()
+		if ctx.IsSet("--name") {
+			meta.Name = argv.Name
+		}
+		if ctx.IsSet("--description") {
+			meta.Description = argv.Description
+		}
+		if ctx.IsSet("--hint") {
+			meta.Hint = argv.Hint
+		}
+		if err := box.SetMeta(meta); err != nil {
+			return err
+		}
+		return box.Info(ctx)
+	},
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"regexp"
+)
+
+// AliasPrefix prefixes alias where an id is expected, e.g. onepw show @gh-work
+const AliasPrefix = "@"
+
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.\-]*$`)
+
+var aliasPasswordHeader = []string{"ID", "ALIAS", "CATEGORY", "ACCOUNT", "PASSWORD", "UPDATED_AT"}
+
+// findAlias returns password by alias, nil returned if not found
+func (box *Box) findAlias(alias string) *Password {
+	if alias == "" {
+		return nil
+	}
+	for _, pw := range box.passwords {
+		if pw.Alias == alias {
+			return pw
+		}
+	}
+	return nil
+}
+
+// checkAlias checks format of alias and whether it's used by another password
+func (box *Box) checkAlias(pw *Password) error {
+	if pw.Alias == "" {
+		return nil
+	}
+	if !aliasPattern.MatchString(pw.Alias) {
+		return newErrInvalidAlias(pw.Alias, "only letters, digits and _.- allowed")
+	}
+	if other := box.findAlias(pw.Alias); other != nil && other.ID != pw.ID {
+		return newErrInvalidAlias(pw.Alias, "used by "+other.ShortID())
+	}
+	return nil
+}
+
+// aliasPasswordSlice is passwordSlice with an alias column
+type aliasPasswordSlice []Password
+
+func (ps aliasPasswordSlice) RowCount() int { return len(ps) }
+func (ps aliasPasswordSlice) ColCount() int {
+	if len(ps) == 0 {
+		return 0
+	}
+	return ps[0].colCount() + 1
+}
+func (ps aliasPasswordSlice) Get(i, j int) string {
+	switch j {
+	case 0:
+		return ps[i].get(0)
+	case 1:
+		return ps[i].Alias
+	}
+	return shorten(ps[i].get(j-1), 32)
+}
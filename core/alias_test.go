@@ -0,0 +1,56 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAlias(t *testing.T) {
+	box := NewBox(NewMemRepository([]byte{}))
+	box.masterPassword = "123456"
+	box.passwords = map[string]*Password{}
+
+	pw := NewPassword("git", "work", "password", "github.com")
+	pw.Alias = "gh-work"
+	id, _, err := box.Add(pw)
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	other := NewPassword("git", "home", "password", "github.com")
+	other.Alias = "gh-work"
+	if _, _, err := box.Add(other); err == nil {
+		t.Errorf("Add duplicated alias want error, got nil")
+	}
+	other.Alias = "bad alias"
+	if _, _, err := box.Add(other); err == nil {
+		t.Errorf("Add invalid alias want error, got nil")
+	}
+
+	passwords, err := box.findPasswords([]string{"@gh-work"}, false)
+	if err != nil || len(passwords) != 1 || passwords[0].ID != id {
+		t.Errorf("findPasswords @gh-work want %s, got %v, %v", id, passwords, err)
+	}
+	if _, err := box.findPasswords([]string{"@gh"}, false); err == nil {
+		t.Errorf("findPasswords @gh want error, got nil")
+	}
+
+	var buf bytes.Buffer
+	if err := box.Find(&buf, "@gh-work", true, false, SortByID); err != nil {
+		t.Fatalf("Find error: %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != "password" {
+		t.Errorf("Find @gh-work want password, got %q", got)
+	}
+
+	// update by alias keeps the alias
+	update := NewEmptyPassword()
+	update.ID = "@gh-work"
+	update.Site = "github.example.com"
+	if _, isNew, err := box.Add(update); err != nil || isNew {
+		t.Errorf("Add update by alias want updated, got new=%v, %v", isNew, err)
+	}
+	if got := box.passwords[id]; got.Site != "github.example.com" || got.Alias != "gh-work" {
+		t.Errorf("update by alias got site %s, alias %s", got.Site, got.Alias)
+	}
+}
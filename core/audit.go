@@ -0,0 +1,229 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mkideal/pkg/textutil"
+)
+
+// Severity of audit finding
+type Severity string
+
+// Severities from the most severe
+const (
+	SeverityHigh   Severity = "high"
+	SeverityMedium Severity = "medium"
+	SeverityLow    Severity = "low"
+)
+
+func (s Severity) level() int {
+	switch s {
+	case SeverityHigh:
+		return 2
+	case SeverityMedium:
+		return 1
+	}
+	return 0
+}
+
+// Checks of audit
+const (
+	CheckMaster = "master" // equal or close to the master password
+	CheckReused = "reused" // used by other passwords
+	CheckWeak   = "weak"   // weak by EstimateStrength
+	CheckOld    = "old"    // not updated for a long time
+	CheckBroken = "broken" // references can't be resolved
+)
+
+// Finding is a problem of password found by Audit
+type Finding struct {
+	ID       string
+	Category string
+	Account  string
+	Check    string
+	Severity Severity
+	Detail   string
+}
+
+// AuditOptions are options of Audit, zero value disables the check
+type AuditOptions struct {
+	// MaxAge reports passwords not updated within the duration
+	MaxAge time.Duration
+
+	// MinScore reports passwords whose strength score is less than it
+	MinScore int
+
+	// MaxDistance reports passwords whose edit distance to master password is
+	// at most it, equal passwords are always reported
+	MaxDistance int
+}
+
+// DefaultAuditOptions is used by onepw audit
+var DefaultAuditOptions = AuditOptions{
+	MaxAge:      180 * 24 * time.Hour,
+	MinScore:    RecommendedScore,
+	MaxDistance: 2,
+}
+
+// Audit checks decrypted passwords which have text password, i.e. login, token
+// and db, and returns findings, the most severe first. Passwords whose references
+// are broken are reported by CheckBroken instead of other checks
+func (box *Box) Audit(opts AuditOptions) ([]Finding, error) {
+	box.RLock()
+	defer box.RUnlock()
+	if box.masterPassword == "" {
+		return nil, errEmptyMasterPassword
+	}
+	findings := []Finding{}
+	add := func(pw *Password, check string, severity Severity, detail string) {
+		findings = append(findings, Finding{
+			ID:       pw.ID,
+			Category: pw.Category,
+			Account:  pw.PlainAccount,
+			Check:    check,
+			Severity: severity,
+			Detail:   detail,
+		})
+	}
+
+	// passwords referring to another password are only checked for broken
+	// references, the referred one is checked instead
+	var passwords []*Password
+	for _, pw := range box.resolveAll(box.find(box.hasTextPassword)) {
+		if pw.broken != nil {
+			add(pw, CheckBroken, SeverityMedium, pw.broken.Error())
+		} else if !IsReference(box.passwords[pw.ID].PlainPassword) {
+			passwords = append(passwords, pw)
+		}
+	}
+
+	users := map[string][]*Password{}
+	for _, pw := range passwords {
+		if pw.PlainPassword != "" {
+			users[pw.PlainPassword] = append(users[pw.PlainPassword], pw)
+		}
+	}
+	now := time.Now()
+	master := strings.ToLower(box.masterPassword)
+	for _, pw := range passwords {
+		if pw.PlainPassword == "" {
+			continue
+		}
+		if pw.PlainPassword == box.masterPassword {
+			add(pw, CheckMaster, SeverityHigh, "equal to the master password")
+		} else if d := editDistance(strings.ToLower(pw.PlainPassword), master); d <= opts.MaxDistance {
+			add(pw, CheckMaster, SeverityHigh, fmt.Sprintf("edit distance %d to the master password", d))
+		}
+		if others := users[pw.PlainPassword]; len(others) > 1 {
+			var ids []string
+			for _, other := range others {
+				if other.ID != pw.ID {
+					ids = append(ids, other.ShortID())
+				}
+			}
+			add(pw, CheckReused, SeverityHigh, "also used by "+strings.Join(ids, ","))
+		}
+		if opts.MinScore > 0 {
+			s := EstimateStrength(pw.PlainPassword, pw.PlainAccount, pw.Category, pw.Site)
+			if s.Score < opts.MinScore {
+				severity := SeverityMedium
+				if s.Score <= 1 {
+					severity = SeverityHigh
+				}
+				add(pw, CheckWeak, severity, s.String())
+			}
+		}
+		if opts.MaxAge > 0 && time.Unix(pw.LastUpdatedAt, 0).Add(opts.MaxAge).Before(now) {
+			days := int(now.Sub(time.Unix(pw.LastUpdatedAt, 0)).Hours() / 24)
+			add(pw, CheckOld, SeverityLow, fmt.Sprintf("not updated for %d days", days))
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return findings[i].Severity.level() > findings[j].Severity.level()
+		}
+		return findings[i].ID < findings[j].ID
+	})
+	return findings, nil
+}
+
+// hasTextPassword reports whether password of the kind is text, i.e. login,
+// token and db
+func (box *Box) hasTextPassword(pw *Password) bool {
+	kind, err := box.lookupKind(pw.Kind)
+	if err != nil {
+		return false
+	}
+	spec, ok := kind.Field(FieldPassword)
+	return ok && spec.Type == FieldText
+}
+
+// editDistance returns Levenshtein distance of a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+var findingHeader = []string{"SEVERITY", "ID", "CATEGORY", "ACCOUNT", "CHECK", "DETAIL"}
+
+// WriteFindings writes findings as table or JSON to specified writer
+func WriteFindings(w io.Writer, findings []Finding, asJSON bool) error {
+	if asJSON {
+		data, err := json.MarshalIndent(findings, "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+	var table textutil.StringMatrix
+	for _, f := range findings {
+		id := f.ID
+		if len(id) > shortIDLength {
+			id = id[:shortIDLength]
+		}
+		table = append(table, []string{
+			string(f.Severity),
+			id,
+			f.Category,
+			shorten(f.Account, 32),
+			f.Check,
+			f.Detail,
+		})
+	}
+	textutil.WriteTable(w, textutil.AddTableHeader(table, findingHeader), nil)
+	return nil
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAudit(t *testing.T) {
+	box := NewBox(NewMemRepository([]byte{}))
+	box.masterPassword = "Master#1234"
+	box.passwords = map[string]*Password{}
+
+	add := func(category, account, password string) string {
+		id, _, err := box.AddDuplicate(NewPassword(category, account, password, ""))
+		if err != nil {
+			t.Fatalf("Add error: %v", err)
+		}
+		return id
+	}
+	strong := add("mail", "alice", "x9$Kq!7Lz@2Pw#Rt")
+	reused1 := add("git", "alice", "u8#Tq!2Mz@9Lw$Vy")
+	reused2 := add("chat", "alice", "u8#Tq!2Mz@9Lw$Vy")
+	weak := add("forum", "bob", "letmein")
+	master := add("bank", "carol", "master#1235")
+	old := add("shop", "dave", "p4$Wn!8Kc@3Zr#Qm")
+	box.passwords[old].LastUpdatedAt = time.Now().Add(-200 * 24 * time.Hour).Unix()
+	copied := add("sso", "alice", "{ref:"+strong+".password}")
+	copied2 := add("wiki", "alice", "{ref:"+copied+".password}")
+	broken := add("vpn", "erin", "{ref:"+weak+".password}")
+	box.passwords[broken].PlainPassword = "{ref:fffffff.password}"
+
+	findings, err := box.Audit(DefaultAuditOptions)
+	if err != nil {
+		t.Fatalf("Audit error: %v", err)
+	}
+	got := map[string][]string{}
+	for _, f := range findings {
+		got[f.ID] = append(got[f.ID], f.Check)
+	}
+	for id, want := range map[string][]string{
+		strong:  nil,
+		reused1: {CheckReused},
+		reused2: {CheckReused},
+		weak:    {CheckWeak},
+		master:  {CheckMaster, CheckWeak},
+		old:     {CheckOld},
+		copied:  nil,
+		copied2: nil,
+		broken:  {CheckBroken},
+	} {
+		ok := len(got[id]) == len(want)
+		for _, check := range want {
+			ok = ok && stringsContains(got[id], check)
+		}
+		if !ok {
+			t.Errorf("%s: want checks %v, got %v", id, want, got[id])
+		}
+	}
+	if findings[0].Severity != SeverityHigh || findings[len(findings)-1].Severity != SeverityLow {
+		t.Errorf("findings want sorted by severity, got %v", findings)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteFindings(&buf, findings, true); err != nil {
+		t.Fatalf("WriteFindings error: %v", err)
+	}
+	var decoded []Finding
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != len(findings) {
+		t.Errorf("WriteFindings JSON want %d findings, got %d, %v", len(findings), len(decoded), err)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"master", "master1", 1},
+	} {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q,%q) want %d, got %d", tt.a, tt.b, tt.want, got)
+		}
+	}
+}
@@ -24,7 +24,14 @@ func init() {
 
 const (
 	masterPasswordID = "0"
-	currentVersion   = 3
+	currentVersion   = 4
+	saltLength       = 64
+
+	// CPU/memory cost of scrypt, boxes created before ScryptN stored use legacyScryptN
+	scryptN       = 1 << 15
+	legacyScryptN = 1 << 12
+	scryptR       = 8
+	scryptP       = 1
 )
 
 // BoxRepository define repo for storing passwords
@@ -35,13 +42,29 @@ type BoxRepository interface {
 
 type boxStore struct {
 	Version   int
+	Meta      BoxMeta
 	Salt      []byte
+	ScryptN   int `json:",omitempty"`
 	Master    Password
 	Passwords []Password
+	Templates []*Kind `json:",omitempty"`
+
+	// Trash stores removed passwords, which purged after TrashMaxAge seconds(0 means never)
+	Trash       []Password `json:",omitempty"`
+	TrashMaxAge int64      `json:",omitempty"`
+}
+
+// scryptN returns CPU/memory cost of scrypt which derives the key
+func (store *boxStore) scryptN() int {
+	if store.ScryptN == 0 {
+		return legacyScryptN
+	}
+	return store.ScryptN
 }
 
 func (store *boxStore) clear() {
 	store.Passwords = store.Passwords[0:0]
+	store.Trash = store.Trash[0:0]
 }
 
 // Box represents password box
@@ -50,6 +73,7 @@ type Box struct {
 	masterPassword string
 	repo           BoxRepository
 	passwords      map[string]*Password
+	trash          map[string]*Password
 
 	store *boxStore
 }
@@ -98,6 +122,7 @@ func NewBox(repo BoxRepository) *Box {
 	box := &Box{
 		repo:      repo,
 		passwords: map[string]*Password{},
+		trash:     map[string]*Password{},
 		store:     &boxStore{Version: currentVersion, Passwords: []Password{}},
 	}
 	return box
@@ -112,7 +137,7 @@ func (box *Box) generateMasterPasswordEntity() (Password, error) {
 	if err := box.initSalt(true); err != nil {
 		return Password{}, err
 	}
-	dk, err := derivedKey(box.masterPassword, box.store.Salt)
+	dk, err := derivedKey(box.masterPassword, box.store.Salt, box.store.scryptN())
 	if err != nil {
 		return Password{}, err
 	}
@@ -129,6 +154,7 @@ func (box *Box) load() error {
 	if err := box.unmarshal(data); err != nil {
 		return err
 	}
+	box.backfillCreatedAt(time.Now())
 
 	// decrypt master password
 	if box.store.Master.ID != "" {
@@ -151,18 +177,19 @@ func (box *Box) load() error {
 			if salt == nil || len(salt) == 0 {
 				got = sha1sum([]byte(box.masterPassword))
 			} else {
-				dk, err := derivedKey(box.masterPassword, box.store.Salt)
+				dk, err := derivedKey(box.masterPassword, box.store.Salt, box.store.scryptN())
 				if err != nil {
 					return err
 				}
 				got = string(dk)
 			}
 			if box.store.Master.PlainPassword != got {
-				return errMasterPassword
+				return newErrMasterPassword(box.store.Meta.Hint)
 			}
 		}
 	}
 
+	box.purgeTrash(time.Now())
 	return nil
 }
 
@@ -195,6 +222,10 @@ func (box *Box) Upgrade() (from, to int, err error) {
 	if err = box.initSalt(false); err != nil {
 		return
 	}
+	// categories are folder paths since version 4
+	for _, pw := range box.passwords {
+		pw.Category = CleanFolder(pw.Category)
+	}
 	if err = box.encryptAll(); err != nil {
 		return
 	}
@@ -202,8 +233,19 @@ func (box *Box) Upgrade() (from, to int, err error) {
 	return
 }
 
-// Add adds a new password to box
+// Add adds a new password to box or updates the password specified by ID, a
+// *DuplicateError returned if the new password without ID duplicates existing passwords
 func (box *Box) Add(pw *Password) (id string, new bool, err error) {
+	return box.add(pw, false)
+}
+
+// AddDuplicate is same as Add but creates the new password even if it duplicates
+// existing passwords
+func (box *Box) AddDuplicate(pw *Password) (id string, new bool, err error) {
+	return box.add(pw, true)
+}
+
+func (box *Box) add(pw *Password, allowDuplicate bool) (id string, new bool, err error) {
 	box.Lock()
 	defer box.Unlock()
 
@@ -212,8 +254,16 @@ func (box *Box) Add(pw *Password) (id string, new bool, err error) {
 		err = errEmptyMasterPassword
 		return
 	}
-	var passwords []*Password
-	if pw.ID != "" {
+	var (
+		passwords     []*Password
+		deriveExpires bool
+		specified     = pw.specified
+	)
+	if strings.HasPrefix(pw.ID, AliasPrefix) {
+		if passwords, err = box.findPasswords([]string{pw.ID}, false); err != nil {
+			return
+		}
+	} else if pw.ID != "" {
 		passwords = box.find(func(p *Password) bool {
 			return strings.HasPrefix(p.ID, pw.ID)
 		})
@@ -224,12 +274,23 @@ func (box *Box) Add(pw *Password) (id string, new bool, err error) {
 		err = newErrAmbiguous(passwords)
 		return
 	} else if len(passwords) == 1 {
-		old := passwords[0]
+		// update a copy, the stored password is replaced after validation
+		old := passwords[0].clone()
+		oldPassword, oldExpiry, oldExpiresAt := old.PlainPassword, old.GetField(fieldExpiry), old.ExpiresAt
 		old.LastUpdatedAt = time.Now().Unix()
 		old.migrate(pw)
+		old.pushHistory(oldPassword, old.LastUpdatedAt)
+		// ExpiresAt follows the updated expiry unless it's updated too
+		deriveExpires = old.GetField(fieldExpiry) != oldExpiry && old.ExpiresAt == oldExpiresAt
 		pw = old
 		new = false
 	} else {
+		if pw.ID == "" && !allowDuplicate {
+			if duplicates := box.duplicates(pw); len(duplicates) > 0 {
+				err = newErrDuplicate(duplicates)
+				return
+			}
+		}
 		if len(pw.ID) < shortIDLength {
 			id, err = box.allocID()
 			if err != nil {
@@ -237,8 +298,19 @@ func (box *Box) Add(pw *Password) (id string, new bool, err error) {
 			}
 			pw.ID = id
 		}
+		deriveExpires = pw.ExpiresAt == 0
 		new = true
 	}
+	pw.Category = CleanFolder(pw.Category)
+	if err = box.checkSpecified(pw, specified); err != nil {
+		return
+	}
+	if err = box.checkAlias(pw); err != nil {
+		return
+	}
+	if err = box.validate(pw, deriveExpires); err != nil {
+		return
+	}
 	if err = box.encrypt(pw, nil); err != nil {
 		return
 	}
@@ -249,8 +321,43 @@ func (box *Box) Add(pw *Password) (id string, new bool, err error) {
 	return
 }
 
-// Remove removes passwords by ids
-func (box *Box) Remove(ids []string, all bool) ([]string, error) {
+// checkSpecified checks that explicitly specified or unset fields are fields of
+// the kind of password, unknown fields are rejected rather than ignored
+func (box *Box) checkSpecified(pw *Password, specified map[string]bool) error {
+	kind, err := box.lookupKind(pw.Kind)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(specified))
+	for name := range specified {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := kind.Field(name); !ok && !isCoreField(name) {
+			return newErrInvalidField(name, "not a field of kind "+kind.Name)
+		}
+	}
+	return nil
+}
+
+// validate validates password by it's kind, see Kind.apply for deriveExpires
+func (box *Box) validate(pw *Password, deriveExpires bool) error {
+	kind, err := box.lookupKind(pw.Kind)
+	if err != nil {
+		return err
+	}
+	kind.apply(pw, deriveExpires)
+	if err := kind.Validate(pw); err != nil {
+		return err
+	}
+	_, err = box.resolve(pw)
+	return err
+}
+
+// Remove removes passwords by ids, passwords are moved to trash unless permanent is true.
+// Passwords referenced by other passwords are not removed unless force is true
+func (box *Box) Remove(ids []string, all, permanent, force bool) ([]string, error) {
 	box.Lock()
 	defer box.Unlock()
 	if box.masterPassword == "" {
@@ -260,11 +367,16 @@ func (box *Box) Remove(ids []string, all bool) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
+	if !force {
+		if err := box.checkReferrers(passwords); err != nil {
+			return nil, err
+		}
+	}
 	deleted := make([]string, 0, len(passwords))
 	for _, pw := range passwords {
 		id := pw.ID
 		if _, ok := box.passwords[id]; ok {
-			delete(box.passwords, id)
+			box.discard(pw, permanent)
 			deleted = append(deleted, id)
 		}
 	}
@@ -275,12 +387,18 @@ func (box *Box) findPasswords(ids []string, all bool) ([]*Password, error) {
 	passwords := make([]*Password, 0, len(ids))
 	for _, id := range ids {
 		size := len(passwords)
-		if foundPw, ok := box.passwords[id]; !ok {
-			for _, pw := range box.passwords {
-				if strings.HasPrefix(pw.ID, id) {
-					passwords = append(passwords, pw)
-				}
+		if strings.HasPrefix(id, AliasPrefix) {
+			pw := box.findAlias(id[len(AliasPrefix):])
+			if pw == nil {
+				return nil, newErrPasswordNotFound(id)
 			}
+			passwords = append(passwords, pw)
+			continue
+		}
+		if foundPw, ok := box.passwords[id]; !ok {
+			passwords = append(passwords, box.find(func(pw *Password) bool {
+				return strings.HasPrefix(pw.ID, id)
+			})...)
 		} else {
 			passwords = append(passwords, foundPw)
 		}
@@ -294,38 +412,69 @@ func (box *Box) findPasswords(ids []string, all bool) ([]*Password, error) {
 	return passwords, nil
 }
 
-// RemoveByAccount removes passwords by category and account
-func (box *Box) RemoveByAccount(category, account string, all bool) ([]string, error) {
+// RemoveByAccount removes passwords by category and account, passwords are moved
+// to trash unless permanent is true, see Remove for force
+func (box *Box) RemoveByAccount(category, account string, all, permanent, force bool) ([]string, error) {
 	box.Lock()
 	defer box.Unlock()
 	if box.masterPassword == "" {
 		return nil, errEmptyMasterPassword
 	}
-	passwords := box.find(func(pw *Password) bool {
-		return pw.Category == category && pw.PlainAccount == account
-	})
+	passwords := box.findByAccount(category, account)
 	if len(passwords) == 0 {
 		return nil, newErrPasswordNotFoundWithAccount(category, account)
 	}
 	if len(passwords) > 1 && !all {
 		return nil, newErrAmbiguous(passwords)
 	}
+	if !force {
+		if err := box.checkReferrers(passwords); err != nil {
+			return nil, err
+		}
+	}
 	ids := []string{}
 	for _, pw := range passwords {
-		delete(box.passwords, pw.ID)
+		box.discard(pw, permanent)
 		ids = append(ids, pw.ID)
 	}
 	return ids, box.save()
 }
 
-// Clear clear password box
-func (box *Box) Clear() ([]string, error) {
+// FindByAccount returns id of the only password which has the category and account
+func (box *Box) FindByAccount(category, account string) (string, error) {
+	box.RLock()
+	defer box.RUnlock()
+	if box.masterPassword == "" {
+		return "", errEmptyMasterPassword
+	}
+	passwords := box.findByAccount(category, account)
+	if len(passwords) == 0 {
+		return "", newErrPasswordNotFoundWithAccount(category, account)
+	}
+	if len(passwords) > 1 {
+		return "", newErrAmbiguous(passwords)
+	}
+	return passwords[0].ID, nil
+}
+
+func (box *Box) findByAccount(category, account string) []*Password {
+	category = CleanFolder(category)
+	return box.find(func(pw *Password) bool {
+		return CleanFolder(pw.Category) == category && pw.PlainAccount == account
+	})
+}
+
+// Clear clear password box, passwords are moved to trash unless permanent is true
+func (box *Box) Clear(permanent bool) ([]string, error) {
 	box.Lock()
 	defer box.Unlock()
+	if box.masterPassword == "" {
+		return nil, errEmptyMasterPassword
+	}
 	ids := make([]string, 0, len(box.passwords))
 	for _, pw := range box.passwords {
 		ids = append(ids, pw.ID)
-		delete(box.passwords, pw.ID)
+		box.discard(pw, permanent)
 	}
 	if len(ids) > 0 {
 		return ids, box.save()
@@ -333,6 +482,7 @@ func (box *Box) Clear() ([]string, error) {
 	return ids, nil
 }
 
+// find returns passwords which satisfy the condition, sorted by ID
 func (box *Box) find(cond func(*Password) bool) []*Password {
 	ret := []*Password{}
 	for _, pw := range box.passwords {
@@ -340,31 +490,62 @@ func (box *Box) find(cond func(*Password) bool) []*Password {
 			ret = append(ret, pw)
 		}
 	}
+	sort.Sort(passwordPtrSlice(ret))
 	return ret
 }
 
-// List writes all passwords to specified writer
-func (box *Box) List(w io.Writer, noHeader, showHidden bool) error {
+// List writes all passwords in the folder to specified writer, empty folder means all
+func (box *Box) List(w io.Writer, folder string, noHeader, showHidden, showAlias bool, sortBy SortBy) error {
 	box.RLock()
 	defer box.RUnlock()
 	if box.masterPassword == "" {
 		return errEmptyMasterPassword
 	}
+	folder = CleanFolder(folder)
+	all := box.sortedPasswords(showHidden)
+	passwords := all[:0]
+	for _, pw := range all {
+		if inFolder(CleanFolder(pw.Category), folder) {
+			// unresolved references are listed as is and marked broken
+			if resolved, err := box.resolve(&pw); err == nil {
+				pw = *resolved
+			} else {
+				pw.broken = err
+			}
+			passwords = append(passwords, pw)
+		}
+	}
+	sortBy.sortValues(passwords)
 	var table textutil.Table
-	table = passwordSlice(box.sortedPasswords(showHidden))
+	header := passwordHeader
+	table = passwordSlice(passwords)
+	if showAlias {
+		header = aliasPasswordHeader
+		table = aliasPasswordSlice(passwords)
+	}
 	if !noHeader {
-		table = textutil.AddTableHeader(table, passwordHeader)
+		table = textutil.AddTableHeader(table, header)
 	}
 	textutil.WriteTable(w, table, box.colorID(w, !noHeader))
 	return nil
 }
 
-// Inspect show low-level information of password
+// Inspect show low-level information of password, and records an use of these passwords
 func (box *Box) Inspect(w io.Writer, ids []string, all bool) error {
+	box.Lock()
+	defer box.Unlock()
+	if box.masterPassword == "" {
+		return errEmptyMasterPassword
+	}
 	passwords, err := box.findPasswords(ids, all)
 	if err != nil {
 		return err
 	}
+	box.touch(passwords)
+	if err := box.save(); err != nil {
+		return err
+	}
+	passwords = box.resolveAll(passwords)
 	sort.Sort(passwordPtrSlice(passwords))
 	prefix := "    "
 	fmt.Fprintf(w, "[\n%s", prefix)
@@ -378,29 +559,56 @@ func (box *Box) Inspect(w io.Writer, ids []string, all bool) error {
 	return nil
 }
 
-// Find finds password by word
-func (box *Box) Find(w io.Writer, word string, justPassword, justFirst bool) error {
-	box.RLock()
-	defer box.RUnlock()
+// Find finds password by word, an use of passwords is recorded if justPassword is true
+func (box *Box) Find(w io.Writer, word string, justPassword, justFirst bool, sortBy SortBy) error {
+	box.Lock()
+	defer box.Unlock()
 
 	if box.masterPassword == "" {
 		return errEmptyMasterPassword
 	}
-	table := passwordPtrSlice(box.find(func(pw *Password) bool {
-		return pw.match(word)
-	}))
+	var table []*Password
+	if strings.HasPrefix(word, AliasPrefix) {
+		if pw := box.findAlias(word[len(AliasPrefix):]); pw != nil {
+			table = append(table, pw)
+		}
+	} else {
+		table = box.find(func(pw *Password) bool {
+			return pw.match(word)
+		})
+	}
+	sortBy.sort(table)
+	return box.writeFound(w, table, justPassword, justFirst)
+}
+
+// writeFound writes found passwords to specified writer
+func (box *Box) writeFound(w io.Writer, table passwordPtrSlice, justPassword, justFirst bool) error {
 	if len(table) == 0 {
 		return nil
 	}
-	sort.Sort(table)
 	if justFirst {
 		table = table[:1]
 	}
 	if justPassword {
+		box.touch(table)
+		if err := box.save(); err != nil {
+			return err
+		}
+	}
+	table = box.resolveAll(table)
+	if justPassword {
+		// raw references of broken passwords are not printed as passwords
+		var broken error
 		for _, pw := range table {
+			if pw.broken != nil {
+				if broken == nil {
+					broken = fmt.Errorf("%s: %v", pw.ShortID(), pw.broken)
+				}
+				continue
+			}
 			fmt.Fprintf(w, "%s\n", pw.PlainPassword)
 		}
-		return nil
+		return broken
 	}
 	var t textutil.Table
 	t = table
@@ -411,6 +619,45 @@ func (box *Box) Find(w io.Writer, word string, justPassword, justFirst bool) err
 	return nil
 }
 
+var expiringHeader = []string{"ID", "CATEGORY", "ACCOUNT", "EXPIRES_AT", "STATUS"}
+
+// Expiring writes passwords which expired or will expire within specified duration
+// to specified writer, and returns number of these passwords
+func (box *Box) Expiring(w io.Writer, within time.Duration) (int, error) {
+	box.RLock()
+	defer box.RUnlock()
+	if box.masterPassword == "" {
+		return 0, errEmptyMasterPassword
+	}
+	now := time.Now()
+	passwords := passwordPtrSlice(box.find(func(pw *Password) bool {
+		return pw.IsExpired(now.Add(within))
+	}))
+	if len(passwords) == 0 {
+		return 0, nil
+	}
+	sort.Slice(passwords, func(i, j int) bool {
+		return passwords[i].ExpiresAt < passwords[j].ExpiresAt
+	})
+	var table textutil.StringMatrix
+	for _, pw := range passwords {
+		status := "expired"
+		if !pw.IsExpired(now) {
+			days := int(time.Unix(pw.ExpiresAt, 0).Sub(now).Hours() / 24)
+			status = fmt.Sprintf("expires in %d days", days)
+		}
+		table = append(table, []string{
+			pw.ShortID(),
+			pw.Category,
+			shorten(pw.PlainAccount, 32),
+			time.Unix(pw.ExpiresAt, 0).Format(time.RFC3339),
+			status,
+		})
+	}
+	textutil.WriteTable(w, textutil.AddTableHeader(table, expiringHeader), box.colorID(w, true))
+	return len(passwords), nil
+}
+
 // color ID style
 type colorIDStyle struct {
 	textutil.DefaultStyle
@@ -444,7 +691,9 @@ func (box *Box) allocID() (string, error) {
 	count := 0
 	for count < 10 {
 		id := md5sum(rand.Int63())
-		if _, ok := box.passwords[id]; !ok {
+		_, inBox := box.passwords[id]
+		_, inTrash := box.trash[id]
+		if !inBox && !inTrash {
 			return id, nil
 		}
 		count++
@@ -457,6 +706,7 @@ func (box *Box) marshal() ([]byte, error) {
 		return nil, err
 	}
 	box.store.Passwords = box.sortedPasswords(true)
+	box.store.Trash = sortedValues(box.trash)
 	return json.MarshalIndent(box.store, "", "    ")
 }
 
@@ -478,36 +728,49 @@ func (box *Box) unmarshal(data []byte) error {
 		pw := &(box.store.Passwords[i])
 		box.passwords[pw.ID] = pw
 	}
+	for i := range box.store.Trash {
+		pw := &(box.store.Trash[i])
+		box.trash[pw.ID] = pw
+	}
 	if box.masterPassword != "" {
 		if err := box.decryptAll(); err != nil {
 			return err
 		}
+		for _, pw := range box.passwords {
+			migrateTokenExpires(pw)
+		}
+		for _, pw := range box.trash {
+			migrateTokenExpires(pw)
+		}
 	}
 	return nil
 }
 
-func derivedKey(password string, salt []byte) ([]byte, error) {
+// derivedKey derives the encryption key from the master password, it's a variable
+// so tests can count the expensive derivations
+var derivedKey = func(password string, salt []byte, n int) ([]byte, error) {
 	if salt == nil || len(salt) == 0 {
 		// Deprecated: insecure
 		return []byte(md5sum([]byte(password))), nil
 	}
-	return scrypt.Key([]byte(password), salt, 4096, 8, 1, 32)
+	return scrypt.Key([]byte(password), salt, n, scryptR, scryptP, 32)
 }
 
 func (box *Box) initSalt(reinit bool) error {
 	salt := box.store.Salt
 	if salt == nil || len(salt) == 0 || reinit {
-		salt = make([]byte, 64)
+		salt = make([]byte, saltLength)
 		if _, err := crand.Read(salt); err != nil {
 			return err
 		}
 		box.store.Salt = salt
+		box.store.ScryptN = scryptN
 	}
 	return nil
 }
 
 func (box *Box) encryptAll() error {
-	dk, err := derivedKey(box.masterPassword, box.store.Salt)
+	dk, err := derivedKey(box.masterPassword, box.store.Salt, box.store.scryptN())
 	if err != nil {
 		return err
 	}
@@ -516,13 +779,18 @@ func (box *Box) encryptAll() error {
 			return err
 		}
 	}
+	for _, pw := range box.trash {
+		if err = box.encrypt(pw, dk); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (box *Box) encrypt(pw *Password, dk []byte) error {
 	if dk == nil {
 		var err error
-		dk, err = derivedKey(box.masterPassword, box.store.Salt)
+		dk, err = derivedKey(box.masterPassword, box.store.Salt, box.store.scryptN())
 		if err != nil {
 			return err
 		}
@@ -545,11 +813,31 @@ func (box *Box) encrypt(pw *Password, dk []byte) error {
 	}
 	pw.CipherAccount = cfbEncrypt(block, pw.AccountIV, []byte(pw.PlainAccount))
 	pw.CipherPassword = cfbEncrypt(block, pw.PasswordIV, []byte(pw.PlainPassword))
+	for i := range pw.Fields {
+		field := &pw.Fields[i]
+		if len(field.IV) != block.BlockSize() {
+			field.IV = make([]byte, block.BlockSize())
+			if _, err := crand.Read(field.IV); err != nil {
+				return err
+			}
+		}
+		field.Cipher = cfbEncrypt(block, field.IV, []byte(field.Value))
+	}
+	for i := range pw.History {
+		h := &pw.History[i]
+		if len(h.IV) != block.BlockSize() {
+			h.IV = make([]byte, block.BlockSize())
+			if _, err := crand.Read(h.IV); err != nil {
+				return err
+			}
+		}
+		h.Cipher = cfbEncrypt(block, h.IV, []byte(h.Password))
+	}
 	return nil
 }
 
 func (box *Box) decryptAll() error {
-	dk, err := derivedKey(box.masterPassword, box.store.Salt)
+	dk, err := derivedKey(box.masterPassword, box.store.Salt, box.store.scryptN())
 	if err != nil {
 		return err
 	}
@@ -558,21 +846,22 @@ func (box *Box) decryptAll() error {
 			return err
 		}
 	}
+	for _, pw := range box.trash {
+		if err = box.decrypt(pw, dk); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (box *Box) decrypt(pw *Password, dk []byte) error {
 	if dk == nil {
 		var err error
-		dk, err = derivedKey(box.masterPassword, box.store.Salt)
+		dk, err = derivedKey(box.masterPassword, box.store.Salt, box.store.scryptN())
 		if err != nil {
 			return err
 		}
 	}
-	dk, err := derivedKey(box.masterPassword, box.store.Salt)
-	if err != nil {
-		return err
-	}
 	block, err := aes.NewCipher(dk)
 	if err != nil {
 		return err
@@ -587,6 +876,22 @@ func (box *Box) decrypt(pw *Password, dk []byte) error {
 	}
 	pw.PlainAccount = string(cfbDecrypt(block, pw.AccountIV, pw.CipherAccount))
 	pw.PlainPassword = string(cfbDecrypt(block, pw.PasswordIV, pw.CipherPassword))
+	for i := range pw.Fields {
+		field := &pw.Fields[i]
+		if len(field.IV) != block.BlockSize() {
+			debug.Panicf("%s: field %s IV.length=%d, want %d", pw.ID, field.Name, len(field.IV), block.BlockSize())
+			return errLengthOfIV
+		}
+		field.Value = string(cfbDecrypt(block, field.IV, field.Cipher))
+	}
+	for i := range pw.History {
+		h := &pw.History[i]
+		if len(h.IV) != block.BlockSize() {
+			debug.Panicf("%s: history IV.length=%d, want %d", pw.ID, len(h.IV), block.BlockSize())
+			return errLengthOfIV
+		}
+		h.Password = string(cfbDecrypt(block, h.IV, h.Cipher))
+	}
 	return nil
 }
 
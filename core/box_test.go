@@ -1,10 +1,12 @@
 package core
 
 import (
+	"bytes"
 	"crypto/aes"
 	"sort"
 	"strconv"
 	"testing"
+	"time"
 )
 
 func bytesEqual(b1, b2 []byte) bool {
@@ -93,7 +95,7 @@ func TestEncryptDecrypt(t *testing.T) {
 	box := NewBox(NewMemRepository([]byte{}))
 	box.masterPassword = "123456"
 
-	box.encrypt(pw)
+	box.encrypt(pw, nil)
 
 	wantCipherAccount := []byte{228, 58, 249, 147, 129, 167, 175}
 	wantCipherPassword := []byte{158, 190, 63, 132, 121, 169, 38, 195}
@@ -107,7 +109,7 @@ func TestEncryptDecrypt(t *testing.T) {
 	pw.PlainAccount = ""
 	pw.PlainPassword = ""
 
-	box.decrypt(pw)
+	box.decrypt(pw, nil)
 	if pw.PlainAccount != "account" {
 		t.Errorf("PlainAccount want %s, got %s", "account", pw.PlainAccount)
 	}
@@ -116,6 +118,40 @@ func TestEncryptDecrypt(t *testing.T) {
 	}
 }
 
+func TestDecryptAllDerivesKeyOnce(t *testing.T) {
+	box := NewBox(NewMemRepository([]byte{}))
+	if err := box.Init("Master#1234"); err != nil {
+		t.Fatal(err)
+	}
+	for _, account := range []string{"alice", "bob", "carol"} {
+		if _, _, err := box.Add(NewPassword("mail", account, "secret", "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := box.Remove([]string{box.find(func(*Password) bool { return true })[0].ID}, false, false, false); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	derive := derivedKey
+	derivedKey = func(password string, salt []byte, n int) ([]byte, error) {
+		calls++
+		return derive(password, salt, n)
+	}
+	defer func() { derivedKey = derive }()
+	if err := box.decryptAll(); err != nil {
+		t.Fatalf("decryptAll error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("decryptAll want key derived once, got %d times", calls)
+	}
+	for _, pw := range box.passwords {
+		if pw.PlainPassword != "secret" {
+			t.Errorf("decryptAll want secret, got %q", pw.PlainPassword)
+		}
+	}
+}
+
 func TestAdd(t *testing.T) {
 	box := NewBox(NewMemRepository([]byte{}))
 	box.masterPassword = "123456"
@@ -141,7 +177,7 @@ func TestAdd(t *testing.T) {
 		wantPlainAccount, wantPlainPassword := pw.PlainAccount, pw.PlainPassword
 		pw.PlainAccount = ""
 		pw.PlainPassword = ""
-		box.decrypt(pw)
+		box.decrypt(pw, nil)
 		if pw.PlainAccount != wantPlainAccount {
 			t.Errorf("PlainAccount want %s, got %s", wantPlainAccount, pw.PlainAccount)
 		}
@@ -152,6 +188,17 @@ func TestAdd(t *testing.T) {
 	if len(box.passwords) != 2 {
 		t.Errorf("passwords size want %d, got %d", 2, len(box.passwords))
 	}
+
+	// invalid update keeps the stored password unchanged
+	invalid := NewPassword("", "", "invalid", "")
+	invalid.ID = "1234567"
+	invalid.Alias = "bad alias"
+	if _, _, err := box.Add(invalid); err == nil {
+		t.Errorf("add invalid alias want error, got nil")
+	}
+	if pw := box.passwords["1234567"]; pw.PlainPassword != "password" || pw.Alias != "" || len(pw.History) != 0 {
+		t.Errorf("invalid update want password unchanged, got %s, %q, %v", pw.PlainPassword, pw.Alias, pw.History)
+	}
 }
 
 func TestRemove(t *testing.T) {
@@ -170,19 +217,19 @@ func TestRemove(t *testing.T) {
 		return pws
 	}
 	box.passwords = genPasswords()
-	if _, err := box.Remove([]string{"12"}, false); err == nil {
+	if _, err := box.Remove([]string{"12"}, false, false, false); err == nil {
 		t.Errorf("Remove passwords want error, got nil")
 		return
 	}
 
-	if ids, err := box.Remove([]string{"1234569"}, false); err != nil {
+	if ids, err := box.Remove([]string{"1234569"}, false, false, false); err != nil {
 		t.Errorf("Remove passwords want nil, got %v", err)
 		return
 	} else if !stringsEqual(ids, []string{"1234569"}) {
 		t.Errorf("Remove passwords incorrect")
 	}
 
-	if ids, err := box.Remove([]string{"12"}, true); err != nil {
+	if ids, err := box.Remove([]string{"12"}, true, false, false); err != nil {
 		t.Errorf("Remove passwords want nil, got %v", err)
 		return
 	} else if !stringsEqual(ids, []string{"1234567", "1234568"}) {
@@ -190,25 +237,100 @@ func TestRemove(t *testing.T) {
 	}
 
 	box.passwords = genPasswords()
-	if _, err := box.RemoveByAccount("category", "not_found", false); err == nil {
+	if _, err := box.RemoveByAccount("category", "not_found", false, false, false); err == nil {
 		t.Errorf("RemoveByAccount want error, got nil")
 		return
 	}
-	if ids, err := box.RemoveByAccount("category", "account", false); err != nil {
+	if ids, err := box.RemoveByAccount("category", "account", false, false, false); err != nil {
 		t.Errorf("RemoveByAccount want nil, got %v", err)
 		return
 	} else if !stringsEqual(ids, []string{"1234567"}) {
 		t.Errorf("RemoveByAccount passwords incorrect")
 		return
 	}
-	if _, err := box.RemoveByAccount("CATEGORY", "ACCOUNT", false); err == nil {
+	if _, err := box.RemoveByAccount("CATEGORY", "ACCOUNT", false, false, false); err == nil {
 		t.Errorf("RemoveByAccount want error, got nil")
 		return
 	}
-	if ids, err := box.RemoveByAccount("CATEGORY", "ACCOUNT", true); err != nil {
+	if ids, err := box.RemoveByAccount("CATEGORY", "ACCOUNT", true, false, false); err != nil {
 		t.Errorf("RemoveByAccount want nil, got %v", err)
 		return
 	} else if !stringsEqual(ids, []string{"1234568", "1234569"}) {
 		t.Errorf("RemoveByAccount passwords incorrect")
 	}
 }
+
+func TestExpiring(t *testing.T) {
+	box := NewBox(NewMemRepository([]byte{}))
+	box.masterPassword = "123456"
+	now := time.Now()
+	pws := map[string]*Password{
+		"1234567": NewPassword("category", "account", "password", "site"),
+		"1234568": NewPassword("category", "account2", "password", "site"),
+		"1234569": NewPassword("category", "account3", "password", "site"),
+	}
+	pws["1234567"].ExpiresAt = now.Add(-time.Hour).Unix()
+	pws["1234568"].ExpiresAt = now.Add(48 * time.Hour).Unix()
+	for id, pw := range pws {
+		pw.ID = id
+	}
+	box.passwords = pws
+	var buf bytes.Buffer
+	if n, err := box.Expiring(&buf, 0); err != nil || n != 1 {
+		t.Errorf("Expiring within 0 want 1, got %d, %v", n, err)
+	}
+	if n, err := box.Expiring(&buf, 72*time.Hour); err != nil || n != 2 {
+		t.Errorf("Expiring within 72h want 2, got %d, %v", n, err)
+	}
+
+	card := NewPassword("bank", "hello", "4111111111111111", "")
+	card.Kind = "card"
+	card.SetField("expiry", "02/27")
+	id, _, err := box.Add(card)
+	if err != nil {
+		t.Fatalf("Add card error: %v", err)
+	}
+	if want := time.Date(2027, 3, 1, 0, 0, 0, 0, time.UTC).Unix() - 1; box.passwords[id].ExpiresAt != want {
+		t.Errorf("card ExpiresAt want %d, got %d", want, box.passwords[id].ExpiresAt)
+	}
+	update := NewEmptyPassword()
+	update.ID = id
+	update.SetField("expiry", "05/28")
+	update.MarkSet("expiry")
+	if _, _, err := box.Add(update); err != nil {
+		t.Fatalf("update card error: %v", err)
+	}
+	if want := time.Date(2028, 6, 1, 0, 0, 0, 0, time.UTC).Unix() - 1; box.passwords[id].ExpiresAt != want {
+		t.Errorf("updated card ExpiresAt want %d, got %d", want, box.passwords[id].ExpiresAt)
+	}
+
+	token := NewPassword("api", "ci", "token", "")
+	token.Kind = "token"
+	token.SetField(FieldExpires, "2027-01-02")
+	migrateTokenExpires(token)
+	if want, _ := ParseDate("2027-01-02"); token.ExpiresAt != want.Unix() || token.GetField(FieldExpires) != "" {
+		t.Errorf("migrated token ExpiresAt want %d, got %d, %v", want.Unix(), token.ExpiresAt, token.Fields)
+	}
+}
+
+func TestFindByAccount(t *testing.T) {
+	box := NewBox(NewMemRepository([]byte{}))
+	box.masterPassword = "123456"
+	box.passwords = map[string]*Password{
+		"1234567": NewPassword("email", "user", "password", ""),
+		"1234568": NewPassword("git", "user", "password", ""),
+		"1234569": NewPassword("git", "user", "password", ""),
+	}
+	for id, pw := range box.passwords {
+		pw.ID = id
+	}
+	if id, err := box.FindByAccount("email/", "user"); err != nil || id != "1234567" {
+		t.Errorf("FindByAccount email want 1234567, got %s, %v", id, err)
+	}
+	if _, err := box.FindByAccount("git", "user"); err == nil {
+		t.Errorf("FindByAccount git want ambiguous error, got nil")
+	}
+	if _, err := box.FindByAccount("email", "nobody"); err == nil {
+		t.Errorf("FindByAccount nobody want not found error, got nil")
+	}
+}
@@ -0,0 +1,223 @@
+package core
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mkideal/pkg/textutil"
+)
+
+// hashPrefixLength is length of prefix of SHA-1 hash which names a range file
+const hashPrefixLength = 5
+
+// BreachDB counts occurrences of passwords in breaches by uppercase hex SHA-1
+type BreachDB interface {
+	Count(hash string) (int, error)
+	Close() error
+}
+
+// OpenBreachDB opens an offline Pwned Passwords database. If path is a directory,
+// it contains range files named by the first 5 characters of hashes, e.g. 5BAA6
+// or 5BAA6.txt, each line of which is SUFFIX:COUNT. Otherwise path is a file
+// whose lines are HASH:COUNT sorted by hash, which is searched by binary search.
+func OpenBreachDB(path string) (BreachDB, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return rangeBreachDB(path), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &sortedBreachDB{f: f, size: info.Size()}, nil
+}
+
+// HashPassword returns uppercase hex SHA-1 of password
+func HashPassword(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// parseHashLine parses a line formatted as HASH:COUNT
+func parseHashLine(line string) (hash string, count int, err error) {
+	line = strings.TrimRight(line, "\r\n")
+	i := strings.IndexByte(line, ':')
+	if i < 0 {
+		return "", 0, fmt.Errorf("invalid line %q of breach database", line)
+	}
+	count, err = strconv.Atoi(strings.TrimSpace(line[i+1:]))
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid count of line %q of breach database", line)
+	}
+	return strings.ToUpper(strings.TrimSpace(line[:i])), count, nil
+}
+
+// rangeBreachDB is a directory of range files
+type rangeBreachDB string
+
+func (db rangeBreachDB) Count(hash string) (int, error) {
+	prefix, suffix := hash[:hashPrefixLength], hash[hashPrefixLength:]
+	var f *os.File
+	for _, name := range []string{prefix, prefix + ".txt", strings.ToLower(prefix), strings.ToLower(prefix) + ".txt"} {
+		var err error
+		if f, err = os.Open(filepath.Join(string(db), name)); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return 0, err
+		}
+	}
+	if f == nil {
+		return 0, fmt.Errorf("range file %s not found in %s", prefix, string(db))
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		s, count, err := parseHashLine(scanner.Text())
+		if err != nil {
+			return 0, err
+		}
+		if s == suffix {
+			return count, nil
+		}
+	}
+	return 0, scanner.Err()
+}
+
+func (db rangeBreachDB) Close() error { return nil }
+
+// sortedBreachDB is a file of lines sorted by hash
+type sortedBreachDB struct {
+	f    *os.File
+	size int64
+}
+
+// lineAt returns the first line which starts at or after pos, start is size of
+// file if no such line
+func (db *sortedBreachDB) lineAt(pos int64) (start int64, line string, err error) {
+	start = pos
+	if pos > 0 {
+		// pos is a line start only if the previous byte is a newline
+		r := bufio.NewReader(io.NewSectionReader(db.f, pos-1, db.size-pos+1))
+		skipped, err := r.ReadString('\n')
+		if err == io.EOF {
+			return db.size, "", nil
+		} else if err != nil {
+			return 0, "", err
+		}
+		start = pos - 1 + int64(len(skipped))
+	}
+	r := bufio.NewReader(io.NewSectionReader(db.f, start, db.size-start))
+	line, err = r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return 0, "", err
+	}
+	if line == "" {
+		return db.size, "", nil
+	}
+	return start, line, nil
+}
+
+func (db *sortedBreachDB) Count(hash string) (int, error) {
+	lo, hi := int64(0), db.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, line, err := db.lineAt(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+		h, count, err := parseHashLine(line)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case h == hash:
+			return count, nil
+		case h < hash:
+			lo = start + int64(len(line))
+		default:
+			hi = mid
+		}
+	}
+	return 0, nil
+}
+
+func (db *sortedBreachDB) Close() error { return db.f.Close() }
+
+var breachHeader = []string{"ID", "CATEGORY", "ACCOUNT", "COUNT"}
+
+// Breach writes passwords found in the breach database to specified writer, and
+// returns number of these passwords, the most breached first. Passwords whose
+// references are broken are skipped, and the first of these errors is returned
+// after the others are checked
+func (box *Box) Breach(w io.Writer, db BreachDB) (int, error) {
+	box.RLock()
+	defer box.RUnlock()
+	if box.masterPassword == "" {
+		return 0, errEmptyMasterPassword
+	}
+	type breached struct {
+		pw    *Password
+		count int
+	}
+	var (
+		found  []breached
+		broken error
+	)
+	for _, resolved := range box.resolveAll(box.find(box.hasTextPassword)) {
+		if resolved.broken != nil {
+			if broken == nil {
+				broken = fmt.Errorf("password %s: %v", resolved.ShortID(), resolved.broken)
+			}
+			continue
+		}
+		// the referred password is checked instead
+		if IsReference(box.passwords[resolved.ID].PlainPassword) || resolved.PlainPassword == "" {
+			continue
+		}
+		count, err := db.Count(HashPassword(resolved.PlainPassword))
+		if err != nil {
+			return 0, err
+		}
+		if count > 0 {
+			found = append(found, breached{resolved, count})
+		}
+	}
+	if len(found) == 0 {
+		return 0, broken
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].count != found[j].count {
+			return found[i].count > found[j].count
+		}
+		return found[i].pw.ID < found[j].pw.ID
+	})
+	var table textutil.StringMatrix
+	for _, b := range found {
+		table = append(table, []string{
+			b.pw.ShortID(),
+			b.pw.Category,
+			shorten(b.pw.PlainAccount, 32),
+			strconv.Itoa(b.count),
+		})
+	}
+	textutil.WriteTable(w, textutil.AddTableHeader(table, breachHeader), box.colorID(w, true))
+	return len(found), broken
+}
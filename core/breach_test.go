@@ -0,0 +1,84 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestBreachDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "onepw-breach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var lines []string
+	for i := 0; i < 1000; i++ {
+		lines = append(lines, fmt.Sprintf("%s:%d", HashPassword(fmt.Sprintf("pw%d", i)), i+1))
+	}
+	sort.Strings(lines)
+	sorted := filepath.Join(dir, "sorted.txt")
+	if err := ioutil.WriteFile(sorted, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ranges := filepath.Join(dir, "range")
+	os.Mkdir(ranges, 0700)
+	hash := HashPassword("password")
+	if hash != "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8" {
+		t.Fatalf("HashPassword want SHA-1 in uppercase hex, got %s", hash)
+	}
+	data := "0018A45C4D1DEF81644B54AB7F969B88D65:1\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n"
+	if err := ioutil.WriteFile(filepath.Join(ranges, "5BAA6.txt"), []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := OpenBreachDB(sorted)
+	if err != nil {
+		t.Fatalf("OpenBreachDB error: %v", err)
+	}
+	defer db.Close()
+	for i := 0; i < 1000; i++ {
+		if count, err := db.Count(HashPassword(fmt.Sprintf("pw%d", i))); err != nil || count != i+1 {
+			t.Errorf("sorted: pw%d want %d, got %d, %v", i, i+1, count, err)
+		}
+	}
+	if count, err := db.Count(hash); err != nil || count != 0 {
+		t.Errorf("sorted: password want 0, got %d, %v", count, err)
+	}
+
+	db, err = OpenBreachDB(ranges)
+	if err != nil {
+		t.Fatalf("OpenBreachDB error: %v", err)
+	}
+	if count, err := db.Count(hash); err != nil || count != 3861493 {
+		t.Errorf("range: password want 3861493, got %d, %v", count, err)
+	}
+	if _, err := db.Count(HashPassword("pw1")); err == nil {
+		t.Errorf("range: missing range file want error, got nil")
+	}
+
+	box := NewBox(NewMemRepository([]byte{}))
+	box.masterPassword = "123456"
+	box.passwords = map[string]*Password{}
+	if _, _, err := box.Add(NewPassword("mail", "alice", "password", "")); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if n, err := box.Breach(&buf, db); err != nil || n != 1 || !strings.Contains(buf.String(), "3861493") {
+		t.Errorf("Breach want 1 breached password, got %d, %v:\n%s", n, err, buf.String())
+	}
+
+	broken := NewPassword("chat", "alice", "{ref:fffffff.password}", "")
+	broken.ID = "1234567"
+	box.passwords[broken.ID] = broken
+	buf.Reset()
+	if n, err := box.Breach(&buf, db); err == nil || n != 1 || !strings.Contains(buf.String(), "3861493") {
+		t.Errorf("Breach with broken reference want 1 breached password and error, got %d, %v:\n%s", n, err, buf.String())
+	}
+}
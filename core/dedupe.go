@@ -0,0 +1,196 @@
+package core
+
+import (
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mkideal/pkg/textutil"
+)
+
+var duplicateHeader = []string{"GROUP", "ID", "CATEGORY", "ACCOUNT", "SITE", "UPDATED_AT"}
+
+// siteKey returns lower case host of site without www., empty if site is invalid
+func siteKey(site string) string {
+	if site == "" {
+		return ""
+	}
+	u, err := parseURL(site)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// duplicateKeys returns keys of password, passwords which have a same key are duplicated
+func (pw *Password) duplicateKeys() []string {
+	account := strings.ToLower(strings.TrimSpace(pw.PlainAccount))
+	if account == "" {
+		return nil
+	}
+	keys := []string{"category\x00" + strings.ToLower(CleanFolder(pw.Category)) + "\x00" + account}
+	if site := siteKey(pw.Site); site != "" {
+		keys = append(keys, "site\x00"+site+"\x00"+account)
+	}
+	return keys
+}
+
+// duplicates returns passwords which duplicate the password
+func (box *Box) duplicates(pw *Password) []*Password {
+	keys := pw.duplicateKeys()
+	return box.find(func(p *Password) bool {
+		if p.ID == pw.ID {
+			return false
+		}
+		for _, key := range p.duplicateKeys() {
+			if stringsContains(keys, key) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// duplicateGroups returns groups of duplicated passwords, the most recently
+// updated password first in each group
+func (box *Box) duplicateGroups() [][]*Password {
+	passwords := box.find(func(*Password) bool { return true })
+	parent := make([]int, len(passwords))
+	for i := range parent {
+		parent[i] = i
+	}
+	var root func(int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+	first := map[string]int{}
+	for i, pw := range passwords {
+		for _, key := range pw.duplicateKeys() {
+			if j, ok := first[key]; ok {
+				parent[root(i)] = root(j)
+			} else {
+				first[key] = i
+			}
+		}
+	}
+	members := map[int][]*Password{}
+	for i, pw := range passwords {
+		members[root(i)] = append(members[root(i)], pw)
+	}
+	groups := [][]*Password{}
+	for _, group := range members {
+		if len(group) < 2 {
+			continue
+		}
+		sort.SliceStable(group, func(i, j int) bool {
+			if group[i].LastUpdatedAt != group[j].LastUpdatedAt {
+				return group[i].LastUpdatedAt > group[j].LastUpdatedAt
+			}
+			return group[i].ID < group[j].ID
+		})
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i][0].ID < groups[j][0].ID
+	})
+	return groups
+}
+
+// ListDuplicates writes groups of duplicated passwords to specified writer and
+// returns number of groups, the first password of each group is kept by MergeDuplicates
+func (box *Box) ListDuplicates(w io.Writer) (int, error) {
+	box.RLock()
+	defer box.RUnlock()
+	if box.masterPassword == "" {
+		return 0, errEmptyMasterPassword
+	}
+	groups := box.duplicateGroups()
+	var table textutil.StringMatrix
+	for i, group := range groups {
+		for _, pw := range group {
+			table = append(table, []string{
+				strconv.Itoa(i + 1),
+				pw.ShortID(),
+				pw.Category,
+				shorten(pw.PlainAccount, 32),
+				shorten(pw.Site, 32),
+				time.Unix(pw.LastUpdatedAt, 0).Format(time.RFC3339),
+			})
+		}
+	}
+	if len(groups) > 0 {
+		textutil.WriteTable(w, textutil.AddTableHeader(table, duplicateHeader), nil)
+	}
+	return len(groups), nil
+}
+
+// MergeDuplicates merges each group of duplicated passwords into the most recently
+// updated one, passwords of others are kept as history, references to others are
+// redirected to it, and others are moved to trash. It returns ids of merged passwords and ids of passwords moved to trash.
+func (box *Box) MergeDuplicates() (merged, removed []string, err error) {
+	box.Lock()
+	defer box.Unlock()
+	if box.masterPassword == "" {
+		return nil, nil, errEmptyMasterPassword
+	}
+	for _, group := range box.duplicateGroups() {
+		primary := group[0]
+		for _, pw := range group[1:] {
+			primary.merge(pw)
+			box.redirectReferences(pw.ID, primary.ID)
+			box.discard(pw, false)
+			removed = append(removed, pw.ID)
+		}
+		merged = append(merged, primary.ID)
+	}
+	if len(merged) == 0 {
+		return merged, removed, nil
+	}
+	return merged, removed, box.save()
+}
+
+// merge merges the duplicated password into pw, the password of other is kept as history
+func (pw *Password) merge(other *Password) {
+	for _, h := range other.History {
+		pw.pushHistory(h.Password, h.ChangedAt)
+	}
+	pw.pushHistory(other.PlainPassword, other.LastUpdatedAt)
+	if pw.Site == "" {
+		pw.Site = other.Site
+	}
+	if pw.Alias == "" {
+		pw.Alias, other.Alias = other.Alias, ""
+	}
+	for _, tag := range other.Tags {
+		if !pw.hasTag(tag) {
+			pw.Tags = append(pw.Tags, tag)
+		}
+	}
+	for _, rule := range other.URLs {
+		found := false
+		for _, r := range pw.URLs {
+			found = found || r == rule
+		}
+		if !found {
+			pw.URLs = append(pw.URLs, rule)
+		}
+	}
+	for _, field := range other.Fields {
+		if pw.GetField(field.Name) == "" {
+			pw.Fields = append(pw.Fields, Field{Name: field.Name, Type: field.Type, Secret: field.Secret, Value: field.Value})
+		}
+	}
+	if other.CreatedAt < pw.CreatedAt {
+		pw.CreatedAt = other.CreatedAt
+	}
+	if other.LastUsedAt > pw.LastUsedAt {
+		pw.LastUsedAt = other.LastUsedAt
+	}
+	pw.UseCount += other.UseCount
+	pw.Favorite = pw.Favorite || other.Favorite
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDuplicates(t *testing.T) {
+	box := NewBox(NewMemRepository([]byte{}))
+	box.masterPassword = "123456"
+	box.passwords = map[string]*Password{}
+
+	first, _, err := box.Add(NewPassword("git", "hello", "password1", "github.com"))
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	box.passwords[first].LastUpdatedAt -= 60
+	_, _, err = box.Add(NewPassword("git/", "Hello", "password2", ""))
+	dup, ok := err.(*DuplicateError)
+	if !ok || len(dup.IDs) != 1 {
+		t.Fatalf("Add same category and account want DuplicateError, got %v", err)
+	}
+	id, _, err := box.Add(&Password{PasswordBasic: PasswordBasic{Category: "code", PlainAccount: "hello", PlainPassword: "password3", Site: "https://www.github.com/login"}})
+	if _, ok := err.(*DuplicateError); !ok {
+		t.Fatalf("Add same site and account want DuplicateError, got %v", err)
+	}
+	if _, _, err := box.Add(NewPassword("git", "world", "password4", "github.com")); err != nil {
+		t.Errorf("Add different account want nil, got %v", err)
+	}
+	if id, _, err = box.AddDuplicate(NewPassword("code", "hello", "password3", "www.github.com")); err != nil {
+		t.Fatalf("AddDuplicate error: %v", err)
+	}
+
+	// update keeps old password as history
+	update := NewEmptyPassword()
+	update.ID = id
+	update.PlainPassword = "password5"
+	if _, _, err := box.Add(update); err != nil {
+		t.Fatalf("Add update error: %v", err)
+	}
+	if h := box.passwords[id].History; len(h) != 1 || h[0].Password != "password3" {
+		t.Errorf("history want [password3], got %v", h)
+	}
+
+	ref, _, err := box.Add(NewPassword("ci", "bot", "{ref:"+first[:shortIDLength]+".password}", ""))
+	if err != nil {
+		t.Fatalf("Add reference error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if n, err := box.ListDuplicates(&buf); err != nil || n != 1 {
+		t.Errorf("ListDuplicates want 1 group, got %d, %v", n, err)
+	}
+	merged, removed, err := box.MergeDuplicates()
+	if err != nil {
+		t.Fatalf("MergeDuplicates error: %v", err)
+	}
+	if len(merged) != 1 || merged[0] != id || len(removed) != 1 {
+		t.Fatalf("MergeDuplicates want %s kept, got %v, removed %v", id, merged, removed)
+	}
+	var history []string
+	for _, h := range box.passwords[id].History {
+		history = append(history, h.Password)
+	}
+	if len(history) != 2 || !stringsContains(history, "password1") || !stringsContains(history, "password3") {
+		t.Errorf("merged history want password1 and password3, got %v", history)
+	}
+	if _, ok := box.trash[removed[0]]; !ok {
+		t.Errorf("merged password %s want moved to trash", removed[0])
+	}
+	if resolved, err := box.resolve(box.passwords[ref]); err != nil || resolved.PlainPassword != "password5" {
+		t.Errorf("reference to merged password want redirected to %s, got %v, %v", id, box.passwords[ref].PlainPassword, err)
+	}
+}
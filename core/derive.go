@@ -0,0 +1,151 @@
+package core
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Character sets of derived passwords, same as LessPass
+const (
+	deriveLowerChars   = LowerChars
+	deriveUpperChars   = UpperChars
+	deriveDigitChars   = DigitChars
+	deriveSpecialChars = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+)
+
+// Range of length of derived passwords
+const (
+	MinDeriveLength = 5
+	MaxDeriveLength = 35
+)
+
+// deriveIterations is number of PBKDF2 iterations of LessPass
+const deriveIterations = 100000
+
+// Derivation represents parameters of a LessPass-style derived password, which
+// is computed from master password, site, account and the derivation
+type Derivation struct {
+	// Counter changes the derived password, starts from 1
+	Counter int
+
+	// Length of derived password
+	Length int
+
+	// Classes used, composed of d(digit), c(lowercase), C(uppercase) and s(special),
+	// empty means all
+	Classes string `json:",omitempty"`
+}
+
+// DefaultDerivation is used if no derivation parameter specified
+var DefaultDerivation = Derivation{Counter: 1, Length: 16, Classes: "dcCs"}
+
+// Validate checks range of length and counter, and classes
+func (d Derivation) Validate() error {
+	if d.Counter < 1 {
+		return fmt.Errorf("counter %d must be positive", d.Counter)
+	}
+	if d.Length < MinDeriveLength || d.Length > MaxDeriveLength {
+		return fmt.Errorf("length %d must be in [%d,%d]", d.Length, MinDeriveLength, MaxDeriveLength)
+	}
+	policy := Policy{Classes: d.Classes}
+	return policy.Validate()
+}
+
+// String returns derivation as comma separated key=value pairs
+func (d Derivation) String() string {
+	s := fmt.Sprintf("counter=%d,length=%d", d.Counter, d.Length)
+	if d.Classes != "" {
+		s += ",classes=" + d.Classes
+	}
+	return s
+}
+
+// charsets returns character sets of classes in LessPass order
+func (d Derivation) charsets() []string {
+	classes := d.Classes
+	if classes == "" {
+		classes = DefaultDerivation.Classes
+	}
+	var charsets []string
+	for _, c := range []struct {
+		class byte
+		chars string
+	}{
+		{ClassLower, deriveLowerChars},
+		{ClassUpper, deriveUpperChars},
+		{ClassDigit, deriveDigitChars},
+		{ClassSpecial, deriveSpecialChars},
+	} {
+		if strings.IndexByte(classes, c.class) >= 0 {
+			charsets = append(charsets, c.chars)
+		}
+	}
+	return charsets
+}
+
+// DerivePassword derives password from master password, site, account and the
+// derivation by the algorithm of LessPass v2, i.e. entropy is PBKDF2-SHA256 of
+// master password with salt site+account+hex(counter), which is consumed to pick
+// characters and at least one character of each class is inserted
+func DerivePassword(master, site, account string, d Derivation) (string, error) {
+	if err := d.Validate(); err != nil {
+		return "", err
+	}
+	salt := site + account + strconv.FormatInt(int64(d.Counter), 16)
+	key := pbkdf2.Key([]byte(master), []byte(salt), deriveIterations, 32, sha256.New)
+	entropy := new(big.Int).SetBytes(key)
+
+	charsets := d.charsets()
+	all := strings.Join(charsets, "")
+	password := make([]byte, 0, d.Length)
+	for len(password) < d.Length-len(charsets) {
+		password = append(password, all[consumeEntropy(entropy, len(all))])
+	}
+	extra := make([]byte, 0, len(charsets))
+	for _, chars := range charsets {
+		extra = append(extra, chars[consumeEntropy(entropy, len(chars))])
+	}
+	for _, c := range extra {
+		i := consumeEntropy(entropy, len(password))
+		password = append(password[:i], append([]byte{c}, password[i:]...)...)
+	}
+	return string(password), nil
+}
+
+// consumeEntropy divides entropy by n and returns the remainder
+func consumeEntropy(entropy *big.Int, n int) int {
+	r := new(big.Int)
+	entropy.QuoRem(entropy, big.NewInt(int64(n)), r)
+	return int(r.Int64())
+}
+
+// derive returns the derived password of pw by master password of box
+func (box *Box) derive(pw *Password) (string, error) {
+	return DerivePassword(box.masterPassword, pw.Site, pw.PlainAccount, *pw.Derived)
+}
+
+// Derive derives password by master password of box, see DerivePassword
+func (box *Box) Derive(site, account string, d Derivation) (string, error) {
+	box.RLock()
+	defer box.RUnlock()
+	if box.masterPassword == "" {
+		return "", errEmptyMasterPassword
+	}
+	return DerivePassword(box.masterPassword, site, account, d)
+}
+
+// DerivedIDs returns ids of derived passwords, which change with the master password
+func (box *Box) DerivedIDs() []string {
+	box.RLock()
+	defer box.RUnlock()
+	ids := []string{}
+	for _, pw := range box.find(func(pw *Password) bool { return pw.Derived != nil }) {
+		ids = append(ids, pw.ID)
+	}
+	return ids
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDerivePassword(t *testing.T) {
+	for i, tt := range []struct {
+		site, account, master string
+		d                     Derivation
+		want                  string
+	}{
+		// test vectors of LessPass
+		{"example.org", "contact@example.org", "password", Derivation{Counter: 1, Length: 16}, "WHLpUL)e00[iHR+w"},
+		{"example.org", "contact@example.org", "password", Derivation{Counter: 1, Length: 16, Classes: "d"}, "8742368585200667"},
+	} {
+		got, err := DerivePassword(tt.master, tt.site, tt.account, tt.d)
+		if err != nil {
+			t.Errorf("%dth: DerivePassword error: %v", i, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%dth: want %s, got %s", i, tt.want, got)
+		}
+	}
+	for _, d := range []Derivation{{Counter: 0, Length: 16}, {Counter: 1, Length: 4}, {Counter: 1, Length: 36}, {Counter: 1, Length: 16, Classes: "x"}} {
+		if _, err := DerivePassword("password", "example.org", "me", d); err == nil {
+			t.Errorf("DerivePassword %v want error, got nil", d)
+		}
+	}
+}
+
+func TestDerivedEntry(t *testing.T) {
+	box := NewBox(NewMemRepository([]byte{}))
+	box.masterPassword = "123456"
+	box.passwords = map[string]*Password{}
+
+	pw := NewPassword("throwaway", "me", "", "example.com")
+	pw.Derived = &Derivation{Counter: 1, Length: 20}
+	id, _, err := box.Add(pw)
+	if err != nil {
+		t.Fatalf("Add derived error: %v", err)
+	}
+	want, _ := DerivePassword("123456", "example.com", "me", *pw.Derived)
+	var buf bytes.Buffer
+	if err := box.Find(&buf, "throwaway", true, false, SortByID); err != nil {
+		t.Fatalf("Find error: %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != want {
+		t.Errorf("Find derived want %s, got %s", want, got)
+	}
+	if box.passwords[id].PlainPassword != "" {
+		t.Errorf("derived password want not stored, got %s", box.passwords[id].PlainPassword)
+	}
+
+	update := NewPassword("", "", "plain", "")
+	update.ID = id
+	if _, _, err := box.Add(update); err == nil {
+		t.Errorf("Add plain password to derived want error, got nil")
+	}
+
+	generated, err := box.Rotate([]string{id})
+	if err != nil {
+		t.Fatalf("Rotate error: %v", err)
+	}
+	want, _ = DerivePassword("123456", "example.com", "me", Derivation{Counter: 2, Length: 20})
+	if generated[id] != want || box.passwords[id].Derived.Counter != 2 || len(box.passwords[id].History) != 0 {
+		t.Errorf("Rotate derived want counter 2 and %s, got %v, %s", want, box.passwords[id].Derived, generated[id])
+	}
+}
@@ -0,0 +1,179 @@
+package core
+
+import (
+	"crypto/aes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/mkideal/pkg/textutil"
+)
+
+// minSaltLength is minimum length of salt of scrypt
+const minSaltLength = 16
+
+// Checks of doctor
+const (
+	CheckEnvMaster  = "env-master" // master password set in environment
+	CheckPermission = "permission" // box file accessible by others
+	CheckVersion    = "version"    // format version below the current
+	CheckLegacyKey  = "legacy-key" // key derived by md5 without salt
+	CheckKDF        = "kdf"        // weak parameters of scrypt
+	CheckIV         = "iv"         // IV of bad length, decryption fails
+	CheckBackup     = "backup"     // no backup or stale backup of box file
+)
+
+// Diagnosis is a risky setting found by doctor with the remediation
+type Diagnosis struct {
+	Check       string
+	Severity    Severity
+	Detail      string
+	Remediation string
+}
+
+// Doctor checks format version, key derivation and IVs of the encrypted box,
+// the master password is not required
+func (box *Box) Doctor() ([]Diagnosis, error) {
+	box.RLock()
+	defer box.RUnlock()
+	store, err := box.loadStore()
+	if err != nil {
+		return nil, err
+	}
+	diagnoses := []Diagnosis{}
+	if store.Master.ID == "" && len(store.Passwords) == 0 && len(store.Trash) == 0 {
+		return diagnoses, nil
+	}
+	add := func(check string, severity Severity, detail, remediation string) {
+		diagnoses = append(diagnoses, Diagnosis{
+			Check:       check,
+			Severity:    severity,
+			Detail:      detail,
+			Remediation: remediation,
+		})
+	}
+	if store.Version < currentVersion {
+		add(CheckVersion, SeverityMedium, fmt.Sprintf("format version %d, current version %d", store.Version, currentVersion), "onepw up")
+	}
+	if len(store.Salt) == 0 {
+		add(CheckLegacyKey, SeverityHigh, "encryption key derived by md5 without salt", "onepw up")
+	} else {
+		if len(store.Salt) < minSaltLength {
+			add(CheckKDF, SeverityMedium, fmt.Sprintf("scrypt salt of %d bytes, want at least %d", len(store.Salt), minSaltLength), "onepw init -u")
+		}
+		if n := store.scryptN(); n < scryptN {
+			add(CheckKDF, SeverityMedium, fmt.Sprintf("scrypt N=%d, want at least %d", n, scryptN), "onepw init -u")
+		}
+	}
+
+	check := func(pw *Password, trash bool) {
+		var bad []string
+		if len(pw.AccountIV) != aes.BlockSize {
+			bad = append(bad, fmt.Sprintf("AccountIV.length=%d", len(pw.AccountIV)))
+		}
+		if len(pw.PasswordIV) != aes.BlockSize {
+			bad = append(bad, fmt.Sprintf("PasswordIV.length=%d", len(pw.PasswordIV)))
+		}
+		for _, field := range pw.Fields {
+			if len(field.IV) != aes.BlockSize {
+				bad = append(bad, fmt.Sprintf("field %s IV.length=%d", field.Name, len(field.IV)))
+			}
+		}
+		for _, h := range pw.History {
+			if len(h.IV) != aes.BlockSize {
+				bad = append(bad, fmt.Sprintf("history IV.length=%d", len(h.IV)))
+				break
+			}
+		}
+		if len(bad) == 0 {
+			return
+		}
+		id := pw.ID
+		if len(id) > shortIDLength {
+			id = id[:shortIDLength]
+		}
+		if trash {
+			id += " in trash"
+		}
+		add(CheckIV, SeverityHigh, fmt.Sprintf("%s: %s, want %d", id, strings.Join(bad, ","), aes.BlockSize), "restore the box file from a backup")
+	}
+	if store.Master.ID != "" {
+		check(&store.Master, false)
+	}
+	for i := range store.Passwords {
+		check(&store.Passwords[i], false)
+	}
+	for i := range store.Trash {
+		check(&store.Trash[i], true)
+	}
+	return diagnoses, nil
+}
+
+// DiagnoseFile checks permission and backups of the box file, a backup is a file
+// in the same directory whose name is the box file name followed by . or ~, e.g.
+// password.data.bak
+func DiagnoseFile(filename string) ([]Diagnosis, error) {
+	diagnoses := []Diagnosis{}
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return diagnoses, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if runtime.GOOS != "windows" {
+		if perm := info.Mode().Perm(); perm&0022 != 0 {
+			diagnoses = append(diagnoses, Diagnosis{CheckPermission, SeverityHigh, fmt.Sprintf("box file mode %v is writable by others", perm), "chmod 600 " + filename})
+		} else if perm&0044 != 0 {
+			diagnoses = append(diagnoses, Diagnosis{CheckPermission, SeverityMedium, fmt.Sprintf("box file mode %v is readable by others", perm), "chmod 600 " + filename})
+		}
+	}
+
+	base := filepath.Base(filename)
+	files, err := ioutil.ReadDir(filepath.Dir(filename))
+	if err != nil {
+		return nil, err
+	}
+	var latest os.FileInfo
+	for _, backup := range files {
+		name := backup.Name()
+		if backup.IsDir() || len(name) <= len(base) || !strings.HasPrefix(name, base) || !strings.ContainsRune(".~", rune(name[len(base)])) {
+			continue
+		}
+		if latest == nil || backup.ModTime().After(latest.ModTime()) {
+			latest = backup
+		}
+	}
+	remediation := fmt.Sprintf("cp -p %s %s.bak", filename, filename)
+	if latest == nil {
+		diagnoses = append(diagnoses, Diagnosis{CheckBackup, SeverityLow, "no backup of box file", remediation})
+	} else if latest.ModTime().Before(info.ModTime()) {
+		diagnoses = append(diagnoses, Diagnosis{CheckBackup, SeverityLow, fmt.Sprintf("latest backup %s is older than box file", latest.Name()), remediation})
+	}
+	return diagnoses, nil
+}
+
+// SortDiagnoses sorts diagnoses, the most severe first
+func SortDiagnoses(diagnoses []Diagnosis) {
+	sort.SliceStable(diagnoses, func(i, j int) bool {
+		return diagnoses[i].Severity.level() > diagnoses[j].Severity.level()
+	})
+}
+
+var diagnosisHeader = []string{"SEVERITY", "CHECK", "DETAIL", "REMEDIATION"}
+
+// WriteDiagnoses writes diagnoses as table to specified writer
+func WriteDiagnoses(w io.Writer, diagnoses []Diagnosis) {
+	if len(diagnoses) == 0 {
+		return
+	}
+	var table textutil.StringMatrix
+	for _, d := range diagnoses {
+		table = append(table, []string{string(d.Severity), d.Check, d.Detail, d.Remediation})
+	}
+	textutil.WriteTable(w, textutil.AddTableHeader(table, diagnosisHeader), nil)
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+)
+
+func diagnosisChecks(diagnoses []Diagnosis) []string {
+	checks := []string{}
+	for _, d := range diagnoses {
+		checks = append(checks, d.Check)
+	}
+	sort.Strings(checks)
+	return checks
+}
+
+func TestDoctor(t *testing.T) {
+	repo := NewMemRepository([]byte{})
+	box := NewBox(repo)
+	if err := box.Init("Master#1234"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := box.Add(NewPassword("mail", "alice", "secret", "")); err != nil {
+		t.Fatal(err)
+	}
+	if diagnoses, err := box.Doctor(); err != nil || len(diagnoses) != 0 {
+		t.Errorf("Doctor of new box want no diagnoses, got %v, %v", diagnoses, err)
+	}
+
+	// tamper the stored box, the master password is not required
+	store, err := box.loadStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Version = 3
+	store.Salt = nil
+	store.Passwords[0].PasswordIV = []byte{1, 2, 3}
+	data, _ := json.Marshal(store)
+	repo.Save(data)
+	diagnoses, err := box.Doctor()
+	if err != nil {
+		t.Fatalf("Doctor error: %v", err)
+	}
+	want := []string{CheckIV, CheckLegacyKey, CheckVersion}
+	if got := diagnosisChecks(diagnoses); !reflect.DeepEqual(got, want) {
+		t.Errorf("Doctor want checks %v, got %v", want, got)
+	}
+
+	store.Version = currentVersion
+	store.Salt = []byte("short")
+	store.Passwords[0].PasswordIV = make([]byte, 16)
+	data, _ = json.Marshal(store)
+	repo.Save(data)
+	diagnoses, _ = box.Doctor()
+	if got := diagnosisChecks(diagnoses); !reflect.DeepEqual(got, []string{CheckKDF}) {
+		t.Errorf("Doctor want checks %v, got %v", []string{CheckKDF}, got)
+	}
+
+	// boxes created before scrypt N stored use legacyScryptN
+	store.Salt = make([]byte, saltLength)
+	store.ScryptN = 0
+	data, _ = json.Marshal(store)
+	repo.Save(data)
+	diagnoses, _ = box.Doctor()
+	if len(diagnoses) != 1 || diagnoses[0].Check != CheckKDF || diagnoses[0].Remediation != "onepw init -u" {
+		t.Errorf("Doctor want scrypt N remediated by onepw init -u, got %v", diagnoses)
+	}
+}
+
+func TestUpdateScryptN(t *testing.T) {
+	repo := NewMemRepository([]byte{})
+	box := NewBox(repo)
+	if err := box.Init("Master#1234"); err != nil {
+		t.Fatal(err)
+	}
+	if box.store.ScryptN != scryptN {
+		t.Errorf("new box want scrypt N %d, got %d", scryptN, box.store.ScryptN)
+	}
+	id, _, err := box.Add(NewPassword("mail", "alice", "secret", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// re-encrypt as a legacy box
+	box.store.ScryptN = 0
+	dk, err := derivedKey(box.masterPassword, box.store.Salt, legacyScryptN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	box.store.Master.PlainPassword = string(dk)
+	if err := box.encryptAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := box.save(); err != nil {
+		t.Fatal(err)
+	}
+	legacy := NewBox(repo)
+	if err := legacy.Init("Master#1234"); err != nil {
+		t.Fatalf("Init legacy box error: %v", err)
+	}
+	if err := legacy.Update("Master#5678"); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+	if diagnoses, err := legacy.Doctor(); err != nil || len(diagnoses) != 0 {
+		t.Errorf("Doctor after Update want no diagnoses, got %v, %v", diagnoses, err)
+	}
+	updated := NewBox(repo)
+	if err := updated.Init("Master#5678"); err != nil {
+		t.Fatalf("Init updated box error: %v", err)
+	}
+	if pw, err := updated.Get(id); err != nil || pw.PlainPassword != "secret" {
+		t.Errorf("Get after Update want secret, got %v, %v", pw, err)
+	}
+}
+
+func TestDiagnoseFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "onepw-doctor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "password.data")
+	if err := ioutil.WriteFile(filename, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chmod(filename, 0644)
+	ioutil.WriteFile(filepath.Join(dir, "password.database"), nil, 0600)
+
+	want := []string{CheckBackup}
+	if runtime.GOOS != "windows" {
+		want = append(want, CheckPermission)
+	}
+	diagnoses, err := DiagnoseFile(filename)
+	if err != nil {
+		t.Fatalf("DiagnoseFile error: %v", err)
+	}
+	if got := diagnosisChecks(diagnoses); !reflect.DeepEqual(got, want) {
+		t.Errorf("DiagnoseFile want checks %v, got %v", want, got)
+	}
+
+	os.Chmod(filename, 0600)
+	backup := filepath.Join(dir, "password.data.bak")
+	ioutil.WriteFile(backup, []byte("{}"), 0600)
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(backup, old, old)
+	if diagnoses, _ := DiagnoseFile(filename); len(diagnoses) != 1 || diagnoses[0].Check != CheckBackup {
+		t.Errorf("DiagnoseFile want stale backup, got %v", diagnoses)
+	}
+	now := time.Now().Add(time.Hour)
+	os.Chtimes(backup, now, now)
+	if diagnoses, _ := DiagnoseFile(filename); len(diagnoses) != 0 {
+		t.Errorf("DiagnoseFile want no diagnoses, got %v", diagnoses)
+	}
+}
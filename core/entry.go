@@ -0,0 +1,251 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const entryDateLayout = "2006-01-02"
+
+// Get returns a decrypted copy of password by id
+func (box *Box) Get(id string) (*Password, error) {
+	box.RLock()
+	defer box.RUnlock()
+	if box.masterPassword == "" {
+		return nil, errEmptyMasterPassword
+	}
+	passwords, err := box.findPasswords([]string{id}, false)
+	if err != nil {
+		return nil, err
+	}
+	pw := passwords[0].clone()
+	pw.specified = nil
+	return pw, nil
+}
+
+// WriteEntry writes plaintext password as TOML which can be parsed by ParseEntry
+func WriteEntry(w io.Writer, pw *Password) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# password %s, lines starting with # are ignored\n", pw.ID)
+	fmt.Fprintf(&buf, "alias = %s\n", strconv.Quote(pw.Alias))
+	fmt.Fprintf(&buf, "kind = %s\n", strconv.Quote(pw.Kind))
+	fmt.Fprintf(&buf, "category = %s\n", strconv.Quote(pw.Category))
+	fmt.Fprintf(&buf, "account = %s\n", strconv.Quote(pw.PlainAccount))
+	fmt.Fprintf(&buf, "password = %s\n", strconv.Quote(pw.PlainPassword))
+	fmt.Fprintf(&buf, "site = %s\n", strconv.Quote(pw.Site))
+	fmt.Fprintf(&buf, "tags = %s\n", quoteStrings(pw.Tags))
+	fmt.Fprintf(&buf, "ext = %s\n", strconv.Quote(pw.Ext))
+	fmt.Fprintf(&buf, "hidden = %t\n", pw.Hidden)
+	expires := ""
+	if pw.ExpiresAt != 0 {
+		expires = time.Unix(pw.ExpiresAt, 0).Format(entryDateLayout)
+	}
+	fmt.Fprintf(&buf, "expires = %s\n", strconv.Quote(expires))
+	urls := make([]string, 0, len(pw.URLs))
+	for _, rule := range pw.URLs {
+		urls = append(urls, rule.String())
+	}
+	fmt.Fprintf(&buf, "urls = %s\n", quoteStrings(urls))
+	fmt.Fprintf(&buf, "\n[fields]\n")
+	for _, field := range pw.Fields {
+		fmt.Fprintf(&buf, "%s = %s\n", strconv.Quote(field.Name), strconv.Quote(field.Value))
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// ParseEntry parses TOML written by WriteEntry, all fields are marked as
+// specified, so values removed from the TOML are cleared when updating
+func ParseEntry(data []byte) (*Password, error) {
+	pw := NewEmptyPassword()
+	pw.MarkSet(FieldCategory, FieldAccount, FieldPassword, FieldSite, FieldTags,
+		FieldExt, FieldHidden, FieldKind, FieldExpires, FieldURLs, FieldFields, FieldAlias)
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if section != "fields" {
+				return nil, fmt.Errorf("line %d: unknown table %s", lineno, section)
+			}
+			continue
+		}
+		key, value, err := parseEntryLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+		if section == "fields" {
+			s, err := parseEntryString(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineno, err)
+			}
+			pw.SetField(key, s)
+			continue
+		}
+		if err := pw.setEntryValue(key, value); err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+func (pw *Password) setEntryValue(key, value string) error {
+	switch key {
+	case "hidden":
+		hidden, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("hidden must be true or false")
+		}
+		pw.Hidden = hidden
+		return nil
+	case "tags", "urls":
+		values, err := parseEntryStrings(value)
+		if err != nil {
+			return err
+		}
+		if key == "tags" {
+			pw.Tags = values
+			return nil
+		}
+		pw.URLs = nil
+		for _, s := range values {
+			rule, err := ParseURLRule(s)
+			if err != nil {
+				return err
+			}
+			pw.URLs = append(pw.URLs, rule)
+		}
+		return nil
+	}
+	s, err := parseEntryString(value)
+	if err != nil {
+		return err
+	}
+	switch key {
+	case "alias":
+		pw.Alias = s
+	case "kind":
+		pw.Kind = s
+	case "category":
+		pw.Category = s
+	case "account":
+		pw.PlainAccount = s
+	case "password":
+		pw.PlainPassword = s
+	case "site":
+		pw.Site = s
+	case "ext":
+		pw.Ext = s
+	case "expires":
+		pw.ExpiresAt = 0
+		if s != "" {
+			t, err := ParseDate(s)
+			if err != nil {
+				return fmt.Errorf("invalid expiry date %s", s)
+			}
+			pw.ExpiresAt = t.Unix()
+		}
+	default:
+		return fmt.Errorf("unknown key %s", key)
+	}
+	return nil
+}
+
+// parseEntryLine splits line formatted as KEY = VALUE, KEY may be quoted
+func parseEntryLine(line string) (key, value string, err error) {
+	if strings.HasPrefix(line, `"`) {
+		key, rest, err := scanEntryString(line)
+		if err != nil {
+			return "", "", err
+		}
+		rest = strings.TrimSpace(rest)
+		if !strings.HasPrefix(rest, "=") {
+			return "", "", fmt.Errorf("missing = after key %s", key)
+		}
+		return key, strings.TrimSpace(rest[1:]), nil
+	}
+	i := strings.Index(line, "=")
+	if i <= 0 {
+		return "", "", fmt.Errorf("expect KEY = VALUE")
+	}
+	return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]), nil
+}
+
+// parseEntryString parses a quoted string value
+func parseEntryString(value string) (string, error) {
+	s, rest, err := scanEntryString(value)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(rest) != "" {
+		return "", fmt.Errorf("unexpected %s after string", rest)
+	}
+	return s, nil
+}
+
+// parseEntryStrings parses an array of quoted strings, e.g. ["a", "b"]
+func parseEntryStrings(value string) ([]string, error) {
+	if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+		return nil, fmt.Errorf("expect array of strings, got %s", value)
+	}
+	values := []string{}
+	rest := strings.TrimSpace(value[1 : len(value)-1])
+	for rest != "" {
+		s, next, err := scanEntryString(rest)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, s)
+		rest = strings.TrimSpace(next)
+		if rest != "" {
+			if rest[0] != ',' {
+				return nil, fmt.Errorf("expect , between strings")
+			}
+			rest = strings.TrimSpace(rest[1:])
+		}
+	}
+	return values, nil
+}
+
+// scanEntryString scans a leading basic("...") or literal('...') string,
+// returns the unquoted string and the rest
+func scanEntryString(s string) (string, string, error) {
+	if s == "" || (s[0] != '"' && s[0] != '\'') {
+		return "", "", fmt.Errorf("expect quoted string, got %s", s)
+	}
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			if quote == '\'' {
+				return s[1:i], s[i+1:], nil
+			}
+			unquoted, err := strconv.Unquote(s[:i+1])
+			return unquoted, s[i+1:], err
+		}
+	}
+	return "", "", fmt.Errorf("unterminated string %s", s)
+}
+
+func quoteStrings(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, s := range values {
+		quoted = append(quoted, strconv.Quote(s))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
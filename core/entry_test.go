@@ -0,0 +1,50 @@
+package core
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestEntryRoundTrip(t *testing.T) {
+	pw := NewPassword("work/aws", "bob", "pa\"ss\nword", "aws.com")
+	pw.ID = "3439d31"
+	pw.Tags = []string{"cloud", "a b"}
+	pw.Hidden = true
+	pw.Kind = "token"
+	pw.URLs = []URLRule{{URL: "console.aws.com", Match: URLMatchHost}}
+	pw.SetField("scopes", "read,write")
+
+	var buf bytes.Buffer
+	if err := WriteEntry(&buf, pw); err != nil {
+		t.Fatalf("WriteEntry error: %v", err)
+	}
+	got, err := ParseEntry(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ParseEntry error: %v\n%s", err, buf.String())
+	}
+	if !reflect.DeepEqual(got.PasswordBasic, pw.PasswordBasic) {
+		t.Errorf("basic: want %+v, got %+v", pw.PasswordBasic, got.PasswordBasic)
+	}
+	if !reflect.DeepEqual(got.URLs, pw.URLs) {
+		t.Errorf("urls: want %v, got %v", pw.URLs, got.URLs)
+	}
+	if v := got.GetField("scopes"); v != "read,write" {
+		t.Errorf("field scopes: want %q, got %q", "read,write", v)
+	}
+}
+
+func TestParseEntryError(t *testing.T) {
+	for i, data := range []string{
+		`hidden = maybe`,
+		`account = "bob`,
+		`unknown = "x"`,
+		`tags = ["a" "b"]`,
+		"[other]\nx = \"y\"",
+		`expires = "tomorrow"`,
+	} {
+		if _, err := ParseEntry([]byte(data)); err == nil {
+			t.Errorf("%dth: want error for %q, got nil", i, data)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/mkideal/pkg/textutil"
 )
@@ -29,6 +30,40 @@ func newErrAmbiguous(passwords []*Password) error {
 	return errors.New(buf.String())
 }
 
+func newErrMasterPassword(hint string) error {
+	if hint == "" {
+		return errMasterPassword
+	}
+	return fmt.Errorf("%v, hint: %s", errMasterPassword, hint)
+}
+
+// DuplicateError is returned if a new password has same category and account, or
+// same site and account with existing passwords
+type DuplicateError struct {
+	IDs   []string
+	table string
+}
+
+func newErrDuplicate(passwords []*Password) error {
+	var buf bytes.Buffer
+	table := passwordPtrSlice(passwords)
+	sort.Stable(table)
+	textutil.WriteTable(&buf, textutil.AddTableHeader(table, passwordHeader), nil)
+	err := &DuplicateError{table: buf.String()}
+	for _, pw := range table {
+		err.IDs = append(err.IDs, pw.ID)
+	}
+	return err
+}
+
+func (err *DuplicateError) Error() string {
+	return "duplicated:\n" + err.table
+}
+
+func newErrWeakPassword(s Strength, minScore int) error {
+	return fmt.Errorf("password too weak(%v), score %d required", s, minScore)
+}
+
 func newErrPasswordNotFound(id string) error {
 	return fmt.Errorf("password %s not found", id)
 }
@@ -36,3 +71,27 @@ func newErrPasswordNotFound(id string) error {
 func newErrPasswordNotFoundWithAccount(category, account string) error {
 	return fmt.Errorf("password by (category=%s,account=%s) not found", category, account)
 }
+
+func newErrUnknownKind(name string) error {
+	return fmt.Errorf("unknown kind %s", name)
+}
+
+func newErrInvalidField(name, reason string) error {
+	return fmt.Errorf("field %s: %s", name, reason)
+}
+
+func newErrFolderNotFound(folder string) error {
+	return fmt.Errorf("folder %s not found", folder)
+}
+
+func newErrInvalidAlias(alias, reason string) error {
+	return fmt.Errorf("alias %s: %s", alias, reason)
+}
+
+func newErrInvalidReference(ref, reason string) error {
+	return fmt.Errorf("reference %s: %s", ref, reason)
+}
+
+func newErrReferenced(id string, referrers []string) error {
+	return fmt.Errorf("password %s is referenced by %s, removing it breaks the references", id, strings.Join(referrers, ","))
+}
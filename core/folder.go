@@ -0,0 +1,215 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/labstack/gommon/color"
+)
+
+// FolderSeparator separates names of nested folders in Category, e.g. work/aws/prod
+const FolderSeparator = "/"
+
+// CleanFolder returns the shortest folder path: spaces around names, empty names
+// and leading or trailing separators are removed
+func CleanFolder(path string) string {
+	names := strings.Split(path, FolderSeparator)
+	cleaned := names[:0]
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			cleaned = append(cleaned, name)
+		}
+	}
+	return strings.Join(cleaned, FolderSeparator)
+}
+
+// inFolder reports whether category is the folder or a sub-folder of the folder
+func inFolder(category, folder string) bool {
+	return folder == "" || category == folder || strings.HasPrefix(category, folder+FolderSeparator)
+}
+
+type folderNode struct {
+	name      string
+	children  map[string]*folderNode
+	passwords []*Password
+}
+
+func newFolderNode(name string) *folderNode {
+	return &folderNode{name: name, children: map[string]*folderNode{}}
+}
+
+func (node *folderNode) add(pw *Password, names []string) {
+	if len(names) == 0 {
+		node.passwords = append(node.passwords, pw)
+		return
+	}
+	child, ok := node.children[names[0]]
+	if !ok {
+		child = newFolderNode(names[0])
+		node.children[names[0]] = child
+	}
+	child.add(pw, names[1:])
+}
+
+func (node *folderNode) count() int {
+	n := len(node.passwords)
+	for _, child := range node.children {
+		n += child.count()
+	}
+	return n
+}
+
+func (node *folderNode) write(w io.Writer, clr *color.Color, indent string) {
+	names := make([]string, 0, len(node.children))
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	total := len(names) + len(node.passwords)
+	i := 0
+	branch := func() (string, string) {
+		i++
+		if i == total {
+			return "└── ", "    "
+		}
+		return "├── ", "│   "
+	}
+	for _, name := range names {
+		child := node.children[name]
+		prefix, childIndent := branch()
+		fmt.Fprintf(w, "%s%s%s (%d)\n", indent, prefix, clr.Bold(name+FolderSeparator), child.count())
+		child.write(w, clr, indent+childIndent)
+	}
+	for _, pw := range node.passwords {
+		prefix, _ := branch()
+		fmt.Fprintf(w, "%s%s%s %s\n", indent, prefix, clr.Cyan(pw.ShortID()), pw.PlainAccount)
+	}
+}
+
+// Tree writes passwords in the folder as a tree to specified writer
+func (box *Box) Tree(w io.Writer, folder string, showHidden bool) error {
+	box.RLock()
+	defer box.RUnlock()
+	if box.masterPassword == "" {
+		return errEmptyMasterPassword
+	}
+	clr := colorOf(w)
+	folder = CleanFolder(folder)
+	root := newFolderNode(folder)
+	for _, pw := range box.find(func(pw *Password) bool {
+		return (showHidden || !pw.Hidden) && inFolder(CleanFolder(pw.Category), folder)
+	}) {
+		if rel := CleanFolder(strings.TrimPrefix(CleanFolder(pw.Category), folder)); rel != "" {
+			root.add(pw, strings.Split(rel, FolderSeparator))
+		} else {
+			root.add(pw, nil)
+		}
+	}
+	fmt.Fprintf(w, "%s (%d)\n", clr.Bold(folder+FolderSeparator), root.count())
+	root.write(w, clr, "")
+	return nil
+}
+
+// MoveFolder moves the folder and all sub-folders into folder dst, returns ids of
+// moved passwords, e.g. moving work/aws into cloud results in cloud/aws
+func (box *Box) MoveFolder(src, dst string) ([]string, error) {
+	box.Lock()
+	defer box.Unlock()
+	src, dst = CleanFolder(src), CleanFolder(dst)
+	if src != "" && dst != "" && inFolder(dst, src) {
+		return nil, fmt.Errorf("cannot move folder %s into itself", src)
+	}
+	return box.replaceFolder(src, dst+FolderSeparator+src[strings.LastIndex(src, FolderSeparator)+1:])
+}
+
+// RenameFolder renames the last name of folder path
+func (box *Box) RenameFolder(path, name string) ([]string, error) {
+	box.Lock()
+	defer box.Unlock()
+	if name = CleanFolder(name); name == "" || strings.Contains(name, FolderSeparator) {
+		return nil, fmt.Errorf("invalid folder name %q", name)
+	}
+	path = CleanFolder(path)
+	parent := ""
+	if i := strings.LastIndex(path, FolderSeparator); i >= 0 {
+		parent = path[:i]
+	}
+	return box.replaceFolder(path, parent+FolderSeparator+name)
+}
+
+// replaceFolder replaces folder path src with dst for all passwords in src
+func (box *Box) replaceFolder(src, dst string) ([]string, error) {
+	if box.masterPassword == "" {
+		return nil, errEmptyMasterPassword
+	}
+	if src = CleanFolder(src); src == "" {
+		return nil, fmt.Errorf("source folder is empty")
+	}
+	passwords := box.find(func(pw *Password) bool {
+		return inFolder(CleanFolder(pw.Category), src)
+	})
+	if len(passwords) == 0 {
+		return nil, newErrFolderNotFound(src)
+	}
+	ids := make([]string, 0, len(passwords))
+	for _, pw := range passwords {
+		pw.Category = CleanFolder(dst + FolderSeparator + strings.TrimPrefix(CleanFolder(pw.Category), src))
+		ids = append(ids, pw.ID)
+	}
+	return ids, box.save()
+}
+
+// Move moves passwords found by word into the category, returns ids of moved passwords
+func (box *Box) Move(word, category string) ([]string, error) {
+	box.Lock()
+	defer box.Unlock()
+	if box.masterPassword == "" {
+		return nil, errEmptyMasterPassword
+	}
+	category = CleanFolder(category)
+	if category == "" {
+		return nil, fmt.Errorf("category is empty")
+	}
+	passwords, err := box.query(word)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(passwords))
+	for _, pw := range passwords {
+		pw.Category = category
+		ids = append(ids, pw.ID)
+	}
+	return ids, box.save()
+}
+
+// RemoveFolder removes all passwords in the folder and sub-folders, passwords are
+// moved to trash unless permanent is true, see Remove for force
+func (box *Box) RemoveFolder(folder string, permanent, force bool) ([]string, error) {
+	box.Lock()
+	defer box.Unlock()
+	if box.masterPassword == "" {
+		return nil, errEmptyMasterPassword
+	}
+	if folder = CleanFolder(folder); folder == "" {
+		return nil, fmt.Errorf("folder is empty")
+	}
+	passwords := box.find(func(pw *Password) bool {
+		return inFolder(CleanFolder(pw.Category), folder)
+	})
+	if len(passwords) == 0 {
+		return nil, newErrFolderNotFound(folder)
+	}
+	if !force {
+		if err := box.checkReferrers(passwords); err != nil {
+			return nil, err
+		}
+	}
+	ids := make([]string, 0, len(passwords))
+	for _, pw := range passwords {
+		box.discard(pw, permanent)
+		ids = append(ids, pw.ID)
+	}
+	return ids, box.save()
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCleanFolder(t *testing.T) {
+	for _, tt := range []struct {
+		path string
+		want string
+	}{
+		{"email", "email"},
+		{"/work/aws/", "work/aws"},
+		{" work // aws /prod", "work/aws/prod"},
+		{"", ""},
+		{"/", ""},
+	} {
+		if got := CleanFolder(tt.path); got != tt.want {
+			t.Errorf("CleanFolder(%q) want %q, got %q", tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestFolders(t *testing.T) {
+	box := NewBox(NewMemRepository([]byte{}))
+	box.masterPassword = "123456"
+	box.passwords = map[string]*Password{
+		"1234567": NewPassword("work/aws/prod", "root", "password", ""),
+		"1234568": NewPassword("work/aws/dev", "dev", "password", ""),
+		"1234569": NewPassword("work/awsx", "other", "password", ""),
+		"1234570": NewPassword("email", "user", "password", ""),
+	}
+	for id, pw := range box.passwords {
+		pw.ID = id
+	}
+
+	var buf bytes.Buffer
+	if err := box.List(&buf, "work/aws", true, false, false, SortByID); err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "root") || !strings.Contains(out, "dev") || strings.Contains(out, "other") {
+		t.Errorf("List work/aws incorrect:\n%s", out)
+	}
+	buf.Reset()
+	if err := box.Tree(&buf, "", false); err != nil {
+		t.Fatalf("Tree error: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "aws/ (2)") || !strings.Contains(out, "work/ (3)") {
+		t.Errorf("Tree incorrect:\n%s", out)
+	}
+
+	if _, err := box.MoveFolder("work/aws", "work/aws/prod"); err == nil {
+		t.Errorf("move folder into itself want error, got nil")
+	}
+	if ids, err := box.RenameFolder("work/aws", "amazon"); err != nil || !stringsEqual(ids, []string{"1234567", "1234568"}) {
+		t.Errorf("RenameFolder want [1234567 1234568], got %v, %v", ids, err)
+	}
+	if got := box.passwords["1234567"].Category; got != "work/amazon/prod" {
+		t.Errorf("category want %s, got %s", "work/amazon/prod", got)
+	}
+	if ids, err := box.MoveFolder("email", "personal"); err != nil || len(ids) != 1 || box.passwords["1234570"].Category != "personal/email" {
+		t.Errorf("MoveFolder email want personal/email, got %v, %v", ids, err)
+	}
+	if ids, err := box.RemoveFolder("work", false, false); err != nil || len(ids) != 3 || len(box.trash) != 3 {
+		t.Errorf("RemoveFolder work want 3 passwords moved to trash, got %v, %v", ids, err)
+	}
+}
@@ -0,0 +1,237 @@
+package core
+
+import (
+	crand "crypto/rand"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Character classes of generated passwords
+const (
+	DigitChars   = "0123456789"
+	LowerChars   = "abcdefghijklmnopqrstuvwxyz"
+	UpperChars   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	SpecialChars = "~!@#$%^&*()-_=+[]{};:,.<>/?"
+)
+
+// SimilarChars contains look-alike characters, e.g. l and 1, O and 0
+const SimilarChars = "il1Lo0O|`'\""
+
+// Generator generates random passwords by crypto/rand, digits, lower and upper
+// letters are used if no class specified, specials only if Special is set
+type Generator struct {
+	Digit   bool
+	Lower   bool
+	Upper   bool
+	Special bool
+
+	// SpecialSet replaces SpecialChars if it's not empty
+	SpecialSet string
+
+	// ExcludeSimilar excludes SimilarChars
+	ExcludeSimilar bool
+
+	// Exclude contains characters never used
+	Exclude string
+}
+
+// excluded returns characters never used
+func (g Generator) excluded() string {
+	if g.ExcludeSimilar {
+		return g.Exclude + SimilarChars
+	}
+	return g.Exclude
+}
+
+// classes returns character sets of specified classes
+func (g Generator) classes() ([]string, error) {
+	all := !g.Digit && !g.Lower && !g.Upper && !g.Special
+	specials := SpecialChars
+	if g.SpecialSet != "" {
+		specials = g.SpecialSet
+	}
+	var classes []string
+	for _, c := range []struct {
+		enabled bool
+		chars   string
+	}{
+		{all || g.Digit, DigitChars},
+		{all || g.Lower, LowerChars},
+		{all || g.Upper, UpperChars},
+		{g.Special, specials},
+	} {
+		if !c.enabled {
+			continue
+		}
+		chars := removeChars(c.chars, g.excluded())
+		if chars == "" {
+			return nil, fmt.Errorf("character set %q is empty after excluding characters", c.chars)
+		}
+		classes = append(classes, chars)
+	}
+	return classes, nil
+}
+
+// Generate generates a password of length n, which contains at least one
+// character of each class
+func (g Generator) Generate(n int) (string, error) {
+	classes, err := g.classes()
+	if err != nil {
+		return "", err
+	}
+	if n < len(classes) {
+		return "", fmt.Errorf("length %d is less than number of character classes %d", n, len(classes))
+	}
+	all := removeChars(strings.Join(classes, ""), "")
+	password := make([]byte, 0, n)
+	for _, chars := range classes {
+		c, err := randChar(chars)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+	for len(password) < n {
+		c, err := randChar(all)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+	if err := shuffle(password); err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
+
+// randInt returns an uniform random integer in [0,max)
+func randInt(max int) (int, error) {
+	i, err := crand.Int(crand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Int64()), nil
+}
+
+func randChar(chars string) (byte, error) {
+	i, err := randInt(len(chars))
+	if err != nil {
+		return 0, err
+	}
+	return chars[i], nil
+}
+
+// shuffle shuffles bytes by Fisher-Yates
+func shuffle(b []byte) error {
+	for i := len(b) - 1; i > 0; i-- {
+		j, err := randInt(i + 1)
+		if err != nil {
+			return err
+		}
+		b[i], b[j] = b[j], b[i]
+	}
+	return nil
+}
+
+// removeChars removes characters in excluded and duplicated characters from chars
+func removeChars(chars, excluded string) string {
+	var buf strings.Builder
+	for i := 0; i < len(chars); i++ {
+		c := chars[i]
+		if strings.IndexByte(excluded, c) < 0 && strings.IndexByte(buf.String(), c) < 0 {
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
+// Placeholders of character classes in pattern, see GeneratePattern
+const (
+	PatternLower   = 'a'
+	PatternUpper   = 'A'
+	PatternDigit   = '9'
+	PatternSpecial = '!'
+	PatternAny     = '*'
+)
+
+// patternChars returns character set of placeholder, empty for literal character
+func (g Generator) patternChars(c byte) string {
+	specials := SpecialChars
+	if g.SpecialSet != "" {
+		specials = g.SpecialSet
+	}
+	chars := ""
+	switch c {
+	case PatternLower:
+		chars = LowerChars
+	case PatternUpper:
+		chars = UpperChars
+	case PatternDigit:
+		chars = DigitChars
+	case PatternSpecial:
+		chars = specials
+	case PatternAny:
+		chars = DigitChars + LowerChars + UpperChars + specials
+	default:
+		return ""
+	}
+	return removeChars(chars, g.excluded())
+}
+
+// GeneratePattern generates a password by pattern, e.g. Aaaa-9999-! or A{2}9{6}.
+// Placeholders a, A, 9, ! and * are replaced by a random lowercase, uppercase,
+// digit, special or any character, {N} repeats the previous placeholder or
+// character N times, \ escapes the next character, other characters are literal.
+// Classes of the generator are ignored, SpecialSet and ExcludeSimilar are used.
+func (g Generator) GeneratePattern(pattern string) (string, error) {
+	var password []byte
+	for i := 0; i < len(pattern); i++ {
+		c, literal := pattern[i], false
+		if c == '\\' {
+			if i+1 == len(pattern) {
+				return "", fmt.Errorf("pattern %q ends with \\", pattern)
+			}
+			i++
+			c, literal = pattern[i], true
+		} else if c == '{' || c == '}' {
+			return "", fmt.Errorf("unexpected %c at %d of pattern %q", c, i, pattern)
+		}
+		count := 1
+		if i+1 < len(pattern) && pattern[i+1] == '{' {
+			end := strings.IndexByte(pattern[i+1:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unclosed { at %d of pattern %q", i+1, pattern)
+			}
+			n, err := strconv.Atoi(pattern[i+2 : i+1+end])
+			if err != nil || n < 0 {
+				return "", fmt.Errorf("invalid repetition count at %d of pattern %q", i+1, pattern)
+			}
+			count = n
+			i += 1 + end
+		}
+		chars := ""
+		if !literal {
+			chars = g.patternChars(c)
+		}
+		if !literal && chars == "" && strings.IndexByte("aA9!*", c) >= 0 {
+			return "", fmt.Errorf("character set of %c is empty after excluding characters", c)
+		}
+		for j := 0; j < count; j++ {
+			if chars == "" {
+				password = append(password, c)
+				continue
+			}
+			r, err := randChar(chars)
+			if err != nil {
+				return "", err
+			}
+			password = append(password, r)
+		}
+	}
+	if len(password) == 0 {
+		return "", fmt.Errorf("pattern %q generates empty password", pattern)
+	}
+	return string(password), nil
+}
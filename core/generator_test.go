@@ -0,0 +1,91 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerator(t *testing.T) {
+	for i, tc := range []struct {
+		gen     Generator
+		length  int
+		classes []string
+	}{
+		{Generator{}, 12, []string{DigitChars, LowerChars, UpperChars}},
+		{Generator{Lower: true, Special: true}, 8, []string{LowerChars, SpecialChars}},
+		{Generator{Digit: true, Lower: true, Upper: true, Special: true}, 4, []string{DigitChars, LowerChars, UpperChars, SpecialChars}},
+		{Generator{Special: true, SpecialSet: "#$"}, 6, []string{"#$"}},
+	} {
+		for n := 0; n < 50; n++ {
+			password, err := tc.gen.Generate(tc.length)
+			if err != nil {
+				t.Fatalf("%dth: Generate error: %v", i, err)
+			}
+			if len(password) != tc.length {
+				t.Errorf("%dth: length want %d, got %d", i, tc.length, len(password))
+			}
+			all := strings.Join(tc.classes, "")
+			for _, class := range tc.classes {
+				if !strings.ContainsAny(password, class) {
+					t.Errorf("%dth: %s want a character of %s", i, password, class)
+				}
+			}
+			for _, c := range password {
+				if !strings.ContainsRune(all, c) {
+					t.Errorf("%dth: %s contains unexpected character %c", i, password, c)
+				}
+			}
+		}
+	}
+}
+
+func TestGeneratorExcludeSimilar(t *testing.T) {
+	gen := Generator{ExcludeSimilar: true}
+	for n := 0; n < 50; n++ {
+		password, err := gen.Generate(32)
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		if strings.ContainsAny(password, SimilarChars) {
+			t.Errorf("%s contains similar characters", password)
+		}
+	}
+	if _, err := (Generator{Special: true, SpecialSet: "|'", ExcludeSimilar: true}).Generate(8); err == nil {
+		t.Errorf("Generate with empty special set want error, got nil")
+	}
+	if _, err := (Generator{}).Generate(2); err == nil {
+		t.Errorf("Generate length less than classes want error, got nil")
+	}
+}
+
+func TestGeneratePattern(t *testing.T) {
+	for i, tc := range []struct {
+		pattern string
+		classes []string // class of each character, empty for literal
+		literal string
+	}{
+		{"Aaaa-9999-!", []string{UpperChars, LowerChars, LowerChars, LowerChars, "", DigitChars, DigitChars, DigitChars, DigitChars, "", SpecialChars}, "Xxxx-xxxx-x"},
+		{"A{2}9{3}", []string{UpperChars, UpperChars, DigitChars, DigitChars, DigitChars}, "xxxxx"},
+		{`\a\{x{3}`, []string{"", "", "", "", ""}, "a{xxx"},
+	} {
+		password, err := (Generator{}).GeneratePattern(tc.pattern)
+		if err != nil {
+			t.Fatalf("%dth: GeneratePattern error: %v", i, err)
+		}
+		if len(password) != len(tc.classes) {
+			t.Fatalf("%dth: %s want length %d, got %d", i, password, len(tc.classes), len(password))
+		}
+		for j, class := range tc.classes {
+			if class == "" && password[j] != tc.literal[j] {
+				t.Errorf("%dth: %s want %c at %d", i, password, tc.literal[j], j)
+			} else if class != "" && strings.IndexByte(class, password[j]) < 0 {
+				t.Errorf("%dth: %s want character of %s at %d", i, password, class, j)
+			}
+		}
+	}
+	for _, pattern := range []string{"", "a{", "a{x}", "{3}", `a\`, "a{0}"} {
+		if _, err := (Generator{}).GeneratePattern(pattern); err == nil {
+			t.Errorf("GeneratePattern %q want error, got nil", pattern)
+		}
+	}
+}
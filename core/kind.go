@@ -0,0 +1,312 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// FieldType represents value type of field
+type FieldType string
+
+// Supported field types
+const (
+	FieldText   FieldType = "text"   // any text
+	FieldInt    FieldType = "int"    // integer
+	FieldPort   FieldType = "port"   // network port: 1~65535
+	FieldDate   FieldType = "date"   // date formatted as YYYY-MM-DD
+	FieldMonth  FieldType = "month"  // month formatted as MM/YY or MM/YYYY
+	FieldCard   FieldType = "card"   // card number, validated by Luhn algorithm
+	FieldCVV    FieldType = "cvv"    // 3 or 4 digits
+	FieldList   FieldType = "list"   // comma separated list
+	FieldSSHKey FieldType = "sshkey" // PEM encoded SSH private key
+)
+
+// Names of fields which stored in PasswordBasic rather than Password.Fields
+const (
+	fieldAccount  = "account"
+	fieldPassword = "password"
+	fieldSite     = "site"
+)
+
+// fieldExpiry is the expiry month of card, which is used as ExpiresAt
+const fieldExpiry = "expiry"
+
+func isBasicField(name string) bool {
+	return name == fieldAccount || name == fieldPassword || name == fieldSite
+}
+
+// FieldSpec describes a field of Kind
+type FieldSpec struct {
+	Name     string
+	Type     FieldType
+	Secret   bool
+	Required bool
+	Usage    string
+}
+
+// Kind represents schema of password entry
+type Kind struct {
+	Name   string
+	Desc   string
+	Fields []FieldSpec
+}
+
+// DefaultKind is kind of password which has no explicit kind
+const DefaultKind = "login"
+
+var builtinKinds = []*Kind{
+	{
+		Name: DefaultKind,
+		Desc: "Website or application login",
+		Fields: []FieldSpec{
+			{Name: fieldAccount, Type: FieldText, Usage: "Account"},
+			{Name: fieldPassword, Type: FieldText, Secret: true, Required: true, Usage: "Password"},
+			{Name: fieldSite, Type: FieldText, Usage: "Website address"},
+		},
+	},
+	{
+		Name: "card",
+		Desc: "Credit or debit card",
+		Fields: []FieldSpec{
+			{Name: fieldAccount, Type: FieldText, Usage: "Cardholder name"},
+			{Name: fieldPassword, Type: FieldCard, Secret: true, Required: true, Usage: "Card number"},
+			{Name: fieldExpiry, Type: FieldMonth, Required: true, Usage: "Expiry date(MM/YY)"},
+			{Name: "cvv", Type: FieldCVV, Secret: true, Usage: "Card verification value"},
+		},
+	},
+	{
+		Name: "ssh",
+		Desc: "SSH private key",
+		Fields: []FieldSpec{
+			{Name: fieldAccount, Type: FieldText, Usage: "User and host, e.g. user@example.com"},
+			{Name: "private_key", Type: FieldSSHKey, Secret: true, Required: true, Usage: "PEM encoded private key"},
+			{Name: "passphrase", Type: FieldText, Secret: true, Usage: "Passphrase of private key"},
+		},
+	},
+	{
+		Name: "token",
+		Desc: "API token",
+		Fields: []FieldSpec{
+			{Name: fieldAccount, Type: FieldText, Usage: "Owner of token"},
+			{Name: fieldPassword, Type: FieldText, Secret: true, Required: true, Usage: "Token"},
+			{Name: fieldSite, Type: FieldText, Usage: "API endpoint"},
+			{Name: "scopes", Type: FieldList, Usage: "Comma separated scopes"},
+		},
+	},
+	{
+		Name: "db",
+		Desc: "Database credential",
+		Fields: []FieldSpec{
+			{Name: "host", Type: FieldText, Required: true, Usage: "Database host"},
+			{Name: "port", Type: FieldPort, Usage: "Database port"},
+			{Name: fieldAccount, Type: FieldText, Required: true, Usage: "Database user"},
+			{Name: fieldPassword, Type: FieldText, Secret: true, Required: true, Usage: "Database password"},
+			{Name: "dbname", Type: FieldText, Usage: "Database name"},
+		},
+	},
+}
+
+// BuiltinKinds returns all builtin kinds
+func BuiltinKinds() []*Kind {
+	return builtinKinds
+}
+
+// LookupKind finds builtin kind by name, empty name means DefaultKind
+func LookupKind(name string) (*Kind, error) {
+	if name == "" {
+		name = DefaultKind
+	}
+	for _, kind := range builtinKinds {
+		if kind.Name == name {
+			return kind, nil
+		}
+	}
+	return nil, newErrUnknownKind(name)
+}
+
+// Field finds field spec by name
+func (kind *Kind) Field(name string) (FieldSpec, bool) {
+	for _, spec := range kind.Fields {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return FieldSpec{}, false
+}
+
+// Validate validates fields of password by the kind
+func (kind *Kind) Validate(pw *Password) error {
+	for _, spec := range kind.Fields {
+		value := pw.GetField(spec.Name)
+		// derived password is not stored
+		if spec.Name == fieldPassword && pw.Derived != nil {
+			if spec.Type != FieldText {
+				return newErrInvalidField(spec.Name, "can't be derived")
+			}
+			if value != "" {
+				return newErrInvalidField(spec.Name, "is derived, unset derived to store a password")
+			}
+			continue
+		}
+		if value == "" {
+			if spec.Required {
+				return newErrInvalidField(spec.Name, "required")
+			}
+			continue
+		}
+		// referenced value is validated by the referenced password
+		if IsReference(value) {
+			continue
+		}
+		if err := spec.Type.validate(value); err != nil {
+			return newErrInvalidField(spec.Name, err.Error())
+		}
+	}
+	for _, field := range pw.Fields {
+		if _, ok := kind.Field(field.Name); !ok {
+			return newErrInvalidField(field.Name, "not a field of kind "+kind.Name)
+		}
+	}
+	return nil
+}
+
+// apply copies type and secret attributes of field specs to extra fields of password,
+// and the expiry month(e.g. expiry of card) is used as ExpiresAt if deriveExpires
+func (kind *Kind) apply(pw *Password, deriveExpires bool) {
+	for i := range pw.Fields {
+		field := &pw.Fields[i]
+		if spec, ok := kind.Field(field.Name); ok {
+			field.Type = spec.Type
+			field.Secret = spec.Secret
+		}
+		if field.Type == FieldMonth && field.Name == fieldExpiry && deriveExpires {
+			if t, err := parseMonth(field.Value); err == nil {
+				pw.ExpiresAt = t.AddDate(0, 1, 0).Unix() - 1
+			}
+		}
+	}
+}
+
+// migrateTokenExpires moves the expires field, which was a field of builtin kind
+// token before ExpiresAt, to ExpiresAt
+func migrateTokenExpires(pw *Password) {
+	if pw.Kind != "token" {
+		return
+	}
+	value := pw.GetField(FieldExpires)
+	if value == "" {
+		return
+	}
+	t, err := ParseDate(value)
+	if err != nil {
+		return
+	}
+	if pw.ExpiresAt == 0 {
+		pw.ExpiresAt = t.Unix()
+	}
+	pw.SetField(FieldExpires, "")
+}
+
+func (typ FieldType) validate(value string) error {
+	switch typ {
+	case FieldInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+	case FieldPort:
+		if port, err := strconv.Atoi(value); err != nil || port <= 0 || port > 65535 {
+			return fmt.Errorf("%q is not a valid port", value)
+		}
+	case FieldDate:
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return fmt.Errorf("%q is not formatted as YYYY-MM-DD", value)
+		}
+	case FieldMonth:
+		if _, err := parseMonth(value); err != nil {
+			return err
+		}
+	case FieldCard:
+		if !luhnValid(value) {
+			return fmt.Errorf("invalid card number")
+		}
+	case FieldCVV:
+		if len(value) < 3 || len(value) > 4 || !isDigits(value) {
+			return fmt.Errorf("must be 3 or 4 digits")
+		}
+	case FieldSSHKey:
+		if _, err := sshFingerprint(value, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// parseMonth parses MM/YY or MM/YYYY, returns the first day of the month
+func parseMonth(value string) (time.Time, error) {
+	for _, layout := range []string{"01/06", "01/2006"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not formatted as MM/YY", value)
+}
+
+// luhnValid checks card number by Luhn algorithm, spaces and dashes are ignored
+func luhnValid(number string) bool {
+	number = strings.NewReplacer(" ", "", "-", "").Replace(number)
+	if len(number) < 12 || len(number) > 19 || !isDigits(number) {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// sshFingerprint returns SHA256 fingerprint of the PEM encoded private key.
+// The public key is still available for passphrase protected keys in new
+// OpenSSH format, so passphrase is only required for old PEM format.
+func sshFingerprint(key, passphrase string) (string, error) {
+	var (
+		signer ssh.Signer
+		err    error
+	)
+	if passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(key), []byte(passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey([]byte(key))
+	}
+	if err == nil {
+		return ssh.FingerprintSHA256(signer.PublicKey()), nil
+	}
+	if e, ok := err.(*ssh.PassphraseMissingError); ok {
+		if e.PublicKey != nil {
+			return ssh.FingerprintSHA256(e.PublicKey), nil
+		}
+		return "", nil
+	}
+	return "", err
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestLuhnValid(t *testing.T) {
+	for _, tt := range []struct {
+		number string
+		valid  bool
+	}{
+		{"4111111111111111", true},
+		{"4111 1111 1111 1111", true},
+		{"5500-0000-0000-0004", true},
+		{"4111111111111112", false},
+		{"411111111111111a", false},
+		{"1234", false},
+	} {
+		if got := luhnValid(tt.number); got != tt.valid {
+			t.Errorf("luhnValid(%q) want %v, got %v", tt.number, tt.valid, got)
+		}
+	}
+}
+
+func TestKindValidate(t *testing.T) {
+	card, err := LookupKind("card")
+	if err != nil {
+		t.Fatalf("LookupKind card: %v", err)
+	}
+	pw := NewPassword("bank", "hello", "4111111111111111", "")
+	if err := card.Validate(pw); err == nil {
+		t.Errorf("missing expiry want error, got nil")
+	}
+	pw.SetField("expiry", "13/27")
+	if err := card.Validate(pw); err == nil {
+		t.Errorf("invalid expiry want error, got nil")
+	}
+	pw.SetField("expiry", "01/27")
+	pw.SetField("cvv", "123")
+	if err := card.Validate(pw); err != nil {
+		t.Errorf("Validate want nil, got %v", err)
+	}
+	pw.SetField("unknown", "value")
+	if err := card.Validate(pw); err == nil {
+		t.Errorf("unknown field want error, got nil")
+	}
+
+	if _, err := LookupKind("not_found"); err == nil {
+		t.Errorf("LookupKind not_found want error, got nil")
+	}
+	if kind, err := LookupKind(""); err != nil || kind.Name != DefaultKind {
+		t.Errorf("LookupKind empty want %s, got %v, %v", DefaultKind, kind, err)
+	}
+}
+
+func TestPasswordFields(t *testing.T) {
+	pw := NewPassword("db", "root", "password", "")
+	pw.Kind = "db"
+	pw.SetField("host", "db.example.com")
+	pw.SetField("port", "5432")
+	if got := pw.GetField("account"); got != "root" {
+		t.Errorf("account want %s, got %s", "root", got)
+	}
+	if got := pw.GetField("host"); got != "db.example.com" {
+		t.Errorf("host want %s, got %s", "db.example.com", got)
+	}
+	if !pw.match("example") {
+		t.Errorf("match host want true, got false")
+	}
+	pw.SetField("port", "")
+	if len(pw.Fields) != 1 {
+		t.Errorf("fields size want %d, got %d", 1, len(pw.Fields))
+	}
+
+	box := NewBox(NewMemRepository([]byte{}))
+	box.masterPassword = "123456"
+	if _, _, err := box.Add(pw); err != nil {
+		t.Fatalf("add password error: %v", err)
+	}
+	pw.Fields[0].Value = ""
+	if err := box.decrypt(pw, nil); err != nil {
+		t.Fatalf("decrypt error: %v", err)
+	}
+	if got := pw.GetField("host"); got != "db.example.com" {
+		t.Errorf("decrypted host want %s, got %s", "db.example.com", got)
+	}
+}
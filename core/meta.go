@@ -0,0 +1,110 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BoxMeta represents metadata of box, which is stored as plaintext
+type BoxMeta struct {
+	Name        string `json:",omitempty"`
+	Description string `json:",omitempty"`
+	CreatedAt   int64  `json:",omitempty"`
+
+	// Hint of master password, shown after failed unlock
+	Hint string `json:",omitempty"`
+}
+
+// Meta returns metadata of box
+func (box *Box) Meta() BoxMeta {
+	box.RLock()
+	defer box.RUnlock()
+	return box.store.Meta
+}
+
+// SetMeta sets name, description and hint of box, creation time is kept
+func (box *Box) SetMeta(meta BoxMeta) error {
+	box.Lock()
+	defer box.Unlock()
+	if box.masterPassword == "" {
+		return errEmptyMasterPassword
+	}
+	if meta.Hint != "" && strings.Contains(strings.ToLower(meta.Hint), strings.ToLower(box.masterPassword)) {
+		return fmt.Errorf("hint must not contain the master password")
+	}
+	meta.CreatedAt = box.store.Meta.CreatedAt
+	box.store.Meta = meta
+	return box.save()
+}
+
+// backfillCreatedAt sets creation time of box if it's unknown, e.g. box created
+// before metadata stored, to creation time of the oldest password or now if
+// no password. It's stored by the next save
+func (box *Box) backfillCreatedAt(now time.Time) {
+	if box.store.Meta.CreatedAt != 0 {
+		return
+	}
+	createdAt := now.Unix()
+	for _, passwords := range []map[string]*Password{box.passwords, box.trash} {
+		for _, pw := range passwords {
+			if pw.CreatedAt != 0 && pw.CreatedAt < createdAt {
+				createdAt = pw.CreatedAt
+			}
+		}
+	}
+	box.store.Meta.CreatedAt = createdAt
+}
+
+// Info writes metadata, format version and number of passwords of box to specified
+// writer, the master password is not required
+func (box *Box) Info(w io.Writer) error {
+	store, err := box.loadStore()
+	if err != nil {
+		return err
+	}
+	createdAt := ""
+	if store.Meta.CreatedAt != 0 {
+		createdAt = time.Unix(store.Meta.CreatedAt, 0).Format(time.RFC3339)
+	}
+	version := strconv.Itoa(store.Version)
+	if store.Version < currentVersion {
+		version += fmt.Sprintf(" (upgrade to %d by `onepw up`)", currentVersion)
+	}
+	for _, kv := range [][2]string{
+		{"NAME", store.Meta.Name},
+		{"DESCRIPTION", store.Meta.Description},
+		{"CREATED_AT", createdAt},
+		{"HINT", store.Meta.Hint},
+		{"VERSION", version},
+		{"PASSWORDS", strconv.Itoa(len(store.Passwords))},
+		{"TRASH", strconv.Itoa(len(store.Trash))},
+	} {
+		fmt.Fprintf(w, "%-12s %s\n", kv[0], kv[1])
+	}
+	return nil
+}
+
+// loadStore loads the encrypted store from repository without the master password
+func (box *Box) loadStore() (*boxStore, error) {
+	data, err := box.repo.Load()
+	if err != nil {
+		return nil, err
+	}
+	store := &boxStore{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, store); err != nil {
+			// passwords array of version 0
+			store.Version = 0
+			if err := json.Unmarshal(data, &store.Passwords); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		store.Version = currentVersion
+	}
+	return store, nil
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBoxMeta(t *testing.T) {
+	repo := NewMemRepository([]byte{})
+	box := NewBox(repo)
+	if err := box.Init("Master#1234"); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	if box.Meta().CreatedAt == 0 {
+		t.Errorf("CreatedAt of new box want non-zero, got 0")
+	}
+	if err := box.SetMeta(BoxMeta{Name: "personal", Hint: "my master#1234"}); err == nil {
+		t.Errorf("SetMeta hint containing master password want error, got nil")
+	}
+	if err := box.SetMeta(BoxMeta{Name: "personal", Hint: "usual one"}); err != nil {
+		t.Fatalf("SetMeta error: %v", err)
+	}
+	if _, _, err := box.Add(NewPassword("email", "user", "password", "")); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+
+	// info without master password
+	var buf bytes.Buffer
+	if err := NewBox(repo).Info(&buf); err != nil {
+		t.Fatalf("Info error: %v", err)
+	}
+	for _, want := range []string{"personal", "usual one", "PASSWORDS    1"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Info want %q, got\n%s", want, buf.String())
+		}
+	}
+
+	err := NewBox(repo).Init("Wrong#12345")
+	if err == nil || !strings.Contains(err.Error(), "hint: usual one") {
+		t.Errorf("Init with wrong master password want hint, got %v", err)
+	}
+}
+
+func TestBackfillCreatedAt(t *testing.T) {
+	repo := NewMemRepository([]byte{})
+	box := NewBox(repo)
+	if err := box.Init("Master#1234"); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	id, _, err := box.Add(NewPassword("email", "user", "password", ""))
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	created := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	box.passwords[id].CreatedAt = created.Unix()
+	if err := box.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// box stored before metadata supported
+	store, err := box.loadStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Meta = BoxMeta{}
+	data, _ := json.Marshal(store)
+	repo.Save(data)
+
+	if err := NewBox(repo).Init("Master#1234"); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := NewBox(repo).Info(&buf); err != nil {
+		t.Fatalf("Info error: %v", err)
+	}
+	if want := time.Unix(created.Unix(), 0).Format(time.RFC3339); !strings.Contains(buf.String(), "CREATED_AT   "+want) {
+		t.Errorf("Info want CREATED_AT %s, got\n%s", want, buf.String())
+	}
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Passphrase generates diceware-style passphrases by crypto/rand
+type Passphrase struct {
+	Words      int
+	Separator  string
+	Capitalize bool
+
+	// Digit inserts a random digit into a random word
+	Digit bool
+
+	// Wordlist replaces the embedded wordlist if it's not empty
+	Wordlist []string
+}
+
+// LoadWordlist reads words from file, one word per line, lines of diceware
+// format like "11111 word" are supported, duplicated words are ignored
+func LoadWordlist(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	words := []string{}
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		word := fields[len(fields)-1]
+		if !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(words) < 2 {
+		return nil, fmt.Errorf("wordlist %s has less than 2 words", filename)
+	}
+	return words, nil
+}
+
+func (p Passphrase) wordlist() []string {
+	if len(p.Wordlist) > 0 {
+		return p.Wordlist
+	}
+	return defaultWords
+}
+
+// Entropy returns entropy of generated passphrases in bits
+func (p Passphrase) Entropy() float64 {
+	bits := float64(p.Words) * math.Log2(float64(len(p.wordlist())))
+	if p.Digit && p.Words > 0 {
+		bits += math.Log2(10) + math.Log2(float64(p.Words))
+	}
+	return bits
+}
+
+// Generate generates a passphrase
+func (p Passphrase) Generate() (string, error) {
+	if p.Words <= 0 {
+		return "", fmt.Errorf("number of words must be positive")
+	}
+	list := p.wordlist()
+	words := make([]string, p.Words)
+	for i := range words {
+		j, err := randInt(len(list))
+		if err != nil {
+			return "", err
+		}
+		words[i] = list[j]
+		if p.Capitalize {
+			r, size := utf8.DecodeRuneInString(words[i])
+			words[i] = string(unicode.ToUpper(r)) + words[i][size:]
+		}
+	}
+	if p.Digit {
+		i, err := randInt(len(words))
+		if err != nil {
+			return "", err
+		}
+		d, err := randInt(10)
+		if err != nil {
+			return "", err
+		}
+		words[i] += strconv.Itoa(d)
+	}
+	return strings.Join(words, p.Separator), nil
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPassphrase(t *testing.T) {
+	if len(defaultWords) != 6*6*6*6 {
+		t.Errorf("embedded wordlist want %d words, got %d", 6*6*6*6, len(defaultWords))
+	}
+	seen := map[string]bool{}
+	for _, word := range defaultWords {
+		if seen[word] {
+			t.Errorf("embedded wordlist has duplicated word %s", word)
+		}
+		seen[word] = true
+	}
+
+	p := Passphrase{Words: 6, Separator: "-", Capitalize: true, Digit: true}
+	passphrase, err := p.Generate()
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	words := strings.Split(passphrase, "-")
+	if len(words) != 6 {
+		t.Fatalf("Generate want 6 words, got %s", passphrase)
+	}
+	if !strings.ContainsAny(passphrase, DigitChars) {
+		t.Errorf("Generate want a digit, got %s", passphrase)
+	}
+	for _, word := range words {
+		if word[0] < 'A' || word[0] > 'Z' {
+			t.Errorf("Generate want capitalized words, got %s", passphrase)
+		}
+	}
+	if want := 6*math.Log2(1296) + math.Log2(10) + math.Log2(6); math.Abs(p.Entropy()-want) > 1e-9 {
+		t.Errorf("Entropy want %f, got %f", want, p.Entropy())
+	}
+	p = Passphrase{Words: 2, Separator: " ", Capitalize: true, Wordlist: []string{"élan"}}
+	if passphrase, err := p.Generate(); err != nil || passphrase != "Élan Élan" {
+		t.Errorf("Generate non-ASCII word want Élan Élan, got %q, %v", passphrase, err)
+	}
+	if _, err := (Passphrase{}).Generate(); err == nil {
+		t.Errorf("Generate 0 words want error, got nil")
+	}
+}
+
+func TestLoadWordlist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "onepw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "words.txt")
+	ioutil.WriteFile(filename, []byte("# comment\n11111 alpha\n11112 beta\nbeta\n\ngamma\n"), 0600)
+	words, err := LoadWordlist(filename)
+	if err != nil || !stringsEqual(words, []string{"alpha", "beta", "gamma"}) {
+		t.Errorf("LoadWordlist want [alpha beta gamma], got %v, %v", words, err)
+	}
+	ioutil.WriteFile(filename, []byte("alpha\n"), 0600)
+	if _, err := LoadWordlist(filename); err == nil {
+		t.Errorf("LoadWordlist of 1 word want error, got nil")
+	}
+}
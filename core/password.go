@@ -4,6 +4,7 @@ import (
 	"crypto/cipher"
 	"encoding/json"
 	"io"
+	"sort"
 	"strings"
 	"time"
 )
@@ -12,14 +13,26 @@ const shortIDLength = 7
 
 type passwordInspect struct {
 	ID            string
+	Alias         string `json:",omitempty"`
+	Kind          string `json:",omitempty"`
 	Category      string
 	Account       string
 	Password      string
 	Site          string
+	URLs          []string `json:",omitempty"`
 	Tags          []string
 	Ext           string
+	Fields        map[string]string `json:",omitempty"`
 	CreatedAt     string
 	LastUpdatedAt string
+	ExpiresAt     string   `json:",omitempty"`
+	LastUsedAt    string   `json:",omitempty"`
+	History       []string `json:",omitempty"`
+	Policy        string   `json:",omitempty"`
+	Derived       string   `json:",omitempty"`
+	Broken        string   `json:",omitempty"`
+	UseCount      int
+	Favorite      bool
 }
 
 // PasswordBasic is basic of Password
@@ -42,6 +55,52 @@ type PasswordBasic struct {
 
 	// Hidden ...
 	Hidden bool `cli:"H,hidden" usage:"Whether to hide the password" dft:"false"`
+
+	// Kind of password, empty means DefaultKind
+	Kind string `json:",omitempty" cli:"k,kind" usage:"Kind of password: login,card,ssh,token,db"`
+
+	// Alias is an unique human-friendly name of password, referred as @ALIAS
+	Alias string `json:",omitempty" cli:"alias" usage:"Unique alias of password, e.g. gh-work referred as @gh-work"`
+}
+
+// History represents an old password, which is always encrypted
+type History struct {
+	Password  string `json:"-"`
+	IV        []byte
+	Cipher    []byte
+	ChangedAt int64 // time when the password was replaced
+}
+
+// maxHistory limits number of old passwords kept in history
+const maxHistory = 20
+
+// pushHistory records old password which was replaced at specified time
+func (pw *Password) pushHistory(old string, at int64) {
+	if old == "" || old == pw.PlainPassword {
+		return
+	}
+	for _, h := range pw.History {
+		if h.Password == old {
+			return
+		}
+	}
+	pw.History = append(pw.History, History{Password: old, ChangedAt: at})
+	sort.SliceStable(pw.History, func(i, j int) bool {
+		return pw.History[i].ChangedAt < pw.History[j].ChangedAt
+	})
+	if n := len(pw.History); n > maxHistory {
+		pw.History = pw.History[n-maxHistory:]
+	}
+}
+
+// Field represents a kind-specific field of password, value is always encrypted
+type Field struct {
+	Name   string
+	Type   FieldType
+	Secret bool
+	Value  string `json:"-"`
+	IV     []byte
+	Cipher []byte
 }
 
 // Password represents entity of password
@@ -64,6 +123,67 @@ type Password struct {
 
 	// Last updated time stamp
 	LastUpdatedAt int64 `cli:"-"`
+
+	// Kind-specific fields
+	Fields []Field `json:",omitempty" cli:"-"`
+
+	// Expiry time stamp, 0 means never expires
+	ExpiresAt int64 `json:",omitempty" cli:"-"`
+
+	// Deleted time stamp of password in trash
+	DeletedAt int64 `json:",omitempty" cli:"-"`
+
+	// Usage: last used time stamp, used count and favorite flag
+	LastUsedAt int64 `json:",omitempty" cli:"-"`
+	UseCount   int   `json:",omitempty" cli:"-"`
+	Favorite   bool  `json:",omitempty" cli:"-"`
+
+	// URLs with match rules, the Site is used as a domain rule additionally
+	URLs []URLRule `json:",omitempty" cli:"-"`
+
+	// History of old passwords, the latest last
+	History []History `json:",omitempty" cli:"-"`
+
+	// Policy required by the site, used to rotate the password
+	Policy *Policy `json:",omitempty" cli:"-"`
+
+	// Derived password is not stored but derived on reading, see DerivePassword
+	Derived *Derivation `json:",omitempty" cli:"-"`
+
+	// explicitly specified fields, see MarkSet
+	specified map[string]bool `cli:"-"`
+
+	// error of resolving references of the copy, see resolveAll
+	broken error `cli:"-"`
+}
+
+// Names of fields which can be specified by MarkSet or cleared by Unset,
+// names of kind-specific fields are allowed too
+const (
+	FieldCategory = "category"
+	FieldAccount  = fieldAccount
+	FieldPassword = fieldPassword
+	FieldSite     = fieldSite
+	FieldTags     = "tags"
+	FieldExt      = "ext"
+	FieldHidden   = "hidden"
+	FieldKind     = "kind"
+	FieldExpires  = "expires"
+	FieldURLs     = "urls"
+	FieldAlias    = "alias"
+	FieldPolicy   = "policy"
+	FieldDerived  = "derived"
+	FieldFields   = "fields" // all kind-specific fields
+)
+
+// isCoreField reports whether name is a field of passwords of all kinds
+func isCoreField(name string) bool {
+	switch name {
+	case FieldCategory, FieldAccount, FieldPassword, FieldSite, FieldTags, FieldExt, FieldHidden,
+		FieldKind, FieldExpires, FieldURLs, FieldAlias, FieldPolicy, FieldDerived, FieldFields:
+		return true
+	}
+	return false
 }
 
 var passwordHeader = []string{"ID", "CATEGORY", "ACCOUNT", "PASSWORD", "UPDATED_AT"}
@@ -77,6 +197,9 @@ func (pw Password) get(i int) string {
 	case 2:
 		return pw.PlainAccount
 	case 3:
+		if pw.broken != nil {
+			return pw.PlainPassword + " (broken)"
+		}
 		return pw.PlainPassword
 	case 4:
 		return time.Unix(pw.LastUpdatedAt, 0).Format(time.RFC3339)
@@ -89,10 +212,15 @@ func (pw Password) colCount() int {
 }
 
 func (pw Password) match(word string) bool {
-	if strings.Contains(pw.ID, word) {
+	return strings.Contains(pw.ID, word) || pw.matchFields(word)
+}
+
+// matchFields is same as match except that ID is ignored
+func (pw Password) matchFields(word string) bool {
+	if strings.Contains(pw.Category, word) {
 		return true
 	}
-	if strings.Contains(pw.Category, word) {
+	if pw.Alias != "" && strings.Contains(pw.Alias, word) {
 		return true
 	}
 	if strings.Contains(pw.PlainAccount, word) {
@@ -101,6 +229,11 @@ func (pw Password) match(word string) bool {
 	if strings.Contains(pw.Site, word) {
 		return true
 	}
+	for _, rule := range pw.URLs {
+		if strings.Contains(rule.URL, word) {
+			return true
+		}
+	}
 	if pw.Tags != nil {
 		for _, tag := range pw.Tags {
 			if strings.Contains(tag, word) {
@@ -108,9 +241,60 @@ func (pw Password) match(word string) bool {
 			}
 		}
 	}
+	for _, field := range pw.Fields {
+		if !field.Secret && strings.Contains(field.Value, word) {
+			return true
+		}
+	}
 	return false
 }
 
+// GetField returns value of field by name
+func (pw *Password) GetField(name string) string {
+	switch name {
+	case fieldAccount:
+		return pw.PlainAccount
+	case fieldPassword:
+		return pw.PlainPassword
+	case fieldSite:
+		return pw.Site
+	}
+	for _, field := range pw.Fields {
+		if field.Name == name {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// SetField sets value of field by name, empty value removes the field
+func (pw *Password) SetField(name, value string) {
+	switch name {
+	case fieldAccount:
+		pw.PlainAccount = value
+		return
+	case fieldPassword:
+		pw.PlainPassword = value
+		return
+	case fieldSite:
+		pw.Site = value
+		return
+	}
+	for i := range pw.Fields {
+		if pw.Fields[i].Name == name {
+			if value == "" {
+				pw.Fields = append(pw.Fields[:i], pw.Fields[i+1:]...)
+			} else {
+				pw.Fields[i].Value = value
+			}
+			return
+		}
+	}
+	if value != "" {
+		pw.Fields = append(pw.Fields, Field{Name: name, Value: value})
+	}
+}
+
 // NewEmptyPassword creates a empty Password entity
 func NewEmptyPassword() *Password {
 	return NewPassword("", "", "", "")
@@ -145,30 +329,210 @@ func (pw *Password) ShortID() string {
 	return pw.ID
 }
 
+// MarkSet marks fields as explicitly specified. If any field of from is marked,
+// migrate copies marked fields only and empty values are copied too, otherwise
+// only non-empty fields are copied.
+func (pw *Password) MarkSet(names ...string) {
+	if pw.specified == nil {
+		pw.specified = map[string]bool{}
+	}
+	for _, name := range names {
+		pw.specified[name] = true
+	}
+}
+
+// NumSet returns number of explicitly specified fields
+func (pw *Password) NumSet() int {
+	return len(pw.specified)
+}
+
+// IsSet reports whether the field is explicitly specified
+func (pw *Password) IsSet(name string) bool {
+	return pw.specified[name]
+}
+
+// Unset clears the field and marks it as explicitly specified
+func (pw *Password) Unset(name string) {
+	switch name {
+	case FieldCategory:
+		pw.Category = ""
+	case FieldTags:
+		pw.Tags = []string{}
+	case FieldExt:
+		pw.Ext = ""
+	case FieldHidden:
+		pw.Hidden = false
+	case FieldKind:
+		pw.Kind = ""
+	case FieldExpires:
+		pw.ExpiresAt = 0
+	case FieldURLs:
+		pw.URLs = nil
+	case FieldAlias:
+		pw.Alias = ""
+	case FieldPolicy:
+		pw.Policy = nil
+	case FieldDerived:
+		pw.Derived = nil
+	case FieldFields:
+		pw.Fields = nil
+	default:
+		pw.SetField(name, "")
+	}
+	pw.MarkSet(name)
+}
+
+// clone returns a deep copy of password
+func (pw *Password) clone() *Password {
+	c := *pw
+	c.Tags = append([]string{}, pw.Tags...)
+	c.Fields = append([]Field(nil), pw.Fields...)
+	c.URLs = append([]URLRule(nil), pw.URLs...)
+	c.History = append([]History(nil), pw.History...)
+	if pw.Policy != nil {
+		policy := *pw.Policy
+		c.Policy = &policy
+	}
+	if pw.Derived != nil {
+		derived := *pw.Derived
+		c.Derived = &derived
+	}
+	return &c
+}
+
 func (pw *Password) migrate(from *Password) {
+	if from.specified != nil {
+		pw.migrateSpecified(from)
+		return
+	}
 	copyNonEmptyString(&pw.PasswordBasic.Category, from.PasswordBasic.Category)
 	copyNonEmptyString(&pw.PasswordBasic.Ext, from.PasswordBasic.Ext)
 	copyNonEmptyString(&pw.PasswordBasic.PlainAccount, from.PasswordBasic.PlainAccount)
 	copyNonEmptyString(&pw.PasswordBasic.PlainPassword, from.PasswordBasic.PlainPassword)
 	copyNonEmptyString(&pw.PasswordBasic.Site, from.PasswordBasic.Site)
+	copyNonEmptyString(&pw.PasswordBasic.Kind, from.PasswordBasic.Kind)
+	copyNonEmptyString(&pw.PasswordBasic.Alias, from.PasswordBasic.Alias)
 
 	if from.PasswordBasic.Tags != nil && len(from.PasswordBasic.Tags) != 0 {
 		pw.PasswordBasic.Tags = make([]string, len(from.PasswordBasic.Tags))
 		copy(pw.PasswordBasic.Tags, from.PasswordBasic.Tags)
 	}
+	for _, field := range from.Fields {
+		pw.SetField(field.Name, field.Value)
+	}
+	if from.ExpiresAt != 0 {
+		pw.ExpiresAt = from.ExpiresAt
+	}
+	if len(from.URLs) != 0 {
+		pw.URLs = make([]URLRule, len(from.URLs))
+		copy(pw.URLs, from.URLs)
+	}
+	if from.Policy != nil {
+		policy := *from.Policy
+		pw.Policy = &policy
+	}
+	if from.Derived != nil {
+		derived := *from.Derived
+		pw.Derived = &derived
+	}
+}
+
+func (pw *Password) migrateSpecified(from *Password) {
+	for name := range from.specified {
+		switch name {
+		case FieldCategory:
+			pw.Category = from.Category
+		case FieldTags:
+			pw.Tags = make([]string, len(from.Tags))
+			copy(pw.Tags, from.Tags)
+		case FieldExt:
+			pw.Ext = from.Ext
+		case FieldHidden:
+			pw.Hidden = from.Hidden
+		case FieldKind:
+			pw.Kind = from.Kind
+		case FieldAlias:
+			pw.Alias = from.Alias
+		case FieldExpires:
+			pw.ExpiresAt = from.ExpiresAt
+		case FieldURLs:
+			pw.URLs = make([]URLRule, len(from.URLs))
+			copy(pw.URLs, from.URLs)
+		case FieldPolicy:
+			pw.Policy = nil
+			if from.Policy != nil {
+				policy := *from.Policy
+				pw.Policy = &policy
+			}
+		case FieldDerived:
+			pw.Derived = nil
+			if from.Derived != nil {
+				derived := *from.Derived
+				pw.Derived = &derived
+			}
+		case FieldFields:
+			pw.Fields = make([]Field, 0, len(from.Fields))
+			for _, field := range from.Fields {
+				pw.Fields = append(pw.Fields, Field{Name: field.Name, Value: field.Value})
+			}
+		default:
+			pw.SetField(name, from.GetField(name))
+		}
+	}
+}
+
+// IsExpired reports whether the password expired at specified time
+func (pw *Password) IsExpired(now time.Time) bool {
+	return pw.ExpiresAt != 0 && pw.ExpiresAt <= now.Unix()
 }
 
 func (pw *Password) inspect(w io.Writer, prefix string) {
 	v := new(passwordInspect)
 	v.ID = pw.ID
+	v.Alias = pw.Alias
+	v.Kind = pw.Kind
 	v.Account = pw.PlainAccount
 	v.Category = pw.Category
 	v.Password = pw.PlainPassword
 	v.Site = pw.Site
+	for _, rule := range pw.URLs {
+		v.URLs = append(v.URLs, rule.String())
+	}
 	v.Tags = pw.Tags
 	v.Ext = pw.Ext
+	if len(pw.Fields) > 0 {
+		v.Fields = make(map[string]string, len(pw.Fields))
+		for _, field := range pw.Fields {
+			v.Fields[field.Name] = field.Value
+			if field.Type == FieldSSHKey {
+				if fp, err := sshFingerprint(field.Value, pw.GetField("passphrase")); err == nil && fp != "" {
+					v.Fields[field.Name+".fingerprint"] = fp
+				}
+			}
+		}
+	}
 	v.CreatedAt = time.Unix(pw.CreatedAt, 0).Format(time.RFC3339)
 	v.LastUpdatedAt = time.Unix(pw.LastUpdatedAt, 0).Format(time.RFC3339)
+	for _, h := range pw.History {
+		v.History = append(v.History, time.Unix(h.ChangedAt, 0).Format(time.RFC3339)+" "+h.Password)
+	}
+	if pw.Policy != nil {
+		v.Policy = pw.Policy.String()
+	}
+	if pw.Derived != nil {
+		v.Derived = pw.Derived.String()
+	}
+	if pw.broken != nil {
+		v.Broken = pw.broken.Error()
+	}
+	if pw.ExpiresAt != 0 {
+		v.ExpiresAt = time.Unix(pw.ExpiresAt, 0).Format(time.RFC3339)
+	}
+	if pw.LastUsedAt != 0 {
+		v.LastUsedAt = time.Unix(pw.LastUsedAt, 0).Format(time.RFC3339)
+	}
+	v.UseCount = pw.UseCount
+	v.Favorite = pw.Favorite
 	if data, err := json.MarshalIndent(v, prefix, "    "); err == nil {
 		w.Write(data)
 	}
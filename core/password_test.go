@@ -47,3 +47,72 @@ func TestCheckPassword(t *testing.T) {
 		}
 	}
 }
+
+func TestPasswordMigrate(t *testing.T) {
+	newOld := func() *Password {
+		pw := NewEmptyPassword()
+		pw.Category = "email"
+		pw.Site = "example.com"
+		pw.PlainAccount = "user"
+		pw.Hidden = true
+		return pw
+	}
+
+	// legacy: empty values never overwrite
+	old := newOld()
+	from := NewEmptyPassword()
+	from.PlainAccount = "user2"
+	old.migrate(from)
+	if old.PlainAccount != "user2" || old.Site != "example.com" || !old.Hidden {
+		t.Errorf("legacy migrate: got %+v", old.PasswordBasic)
+	}
+
+	// explicit: specified fields are copied even if empty or false
+	old = newOld()
+	from = NewEmptyPassword()
+	from.MarkSet(FieldHidden)
+	from.Unset(FieldSite)
+	old.migrate(from)
+	if old.Hidden {
+		t.Errorf("explicit migrate: want hidden false, got true")
+	}
+	if old.Site != "" {
+		t.Errorf("explicit migrate: want empty site, got %q", old.Site)
+	}
+	if old.Category != "email" || old.PlainAccount != "user" {
+		t.Errorf("explicit migrate: unspecified fields changed: %+v", old.PasswordBasic)
+	}
+}
+
+func TestUnsetUnknownField(t *testing.T) {
+	box := NewBox(NewMemRepository([]byte{}))
+	box.masterPassword = "123456"
+	box.passwords = map[string]*Password{}
+	card := NewPassword("bank", "hello", "4111111111111111", "")
+	card.Kind = "card"
+	card.SetField("expiry", "02/27")
+	card.SetField("cvv", "123")
+	id, _, err := box.Add(card)
+	if err != nil {
+		t.Fatalf("Add card error: %v", err)
+	}
+	for _, tc := range []struct {
+		name string
+		ok   bool
+	}{
+		{"cvv", true},
+		{FieldSite, true},
+		{"pasword", false},
+		{"scopes", false},
+	} {
+		update := NewEmptyPassword()
+		update.ID = id
+		update.Unset(tc.name)
+		if _, _, err := box.Add(update); (err == nil) != tc.ok {
+			t.Errorf("unset %s want ok %v, got error %v", tc.name, tc.ok, err)
+		}
+	}
+	if got := box.passwords[id].GetField("cvv"); got != "" {
+		t.Errorf("unset cvv want empty, got %q", got)
+	}
+}
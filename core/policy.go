@@ -0,0 +1,322 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mkideal/pkg/textutil"
+)
+
+// Character classes of Policy
+const (
+	ClassDigit   = 'd'
+	ClassLower   = 'c'
+	ClassUpper   = 'C'
+	ClassSpecial = 's'
+)
+
+// Keys of policy specified by ParsePolicy
+const (
+	PolicyLength    = "length"
+	PolicyMaxLength = "max-length"
+	PolicyClasses   = "classes"
+	PolicyForbidden = "forbidden"
+	PolicyPattern   = "pattern"
+)
+
+// Policy represents requirements of the site on password, it's used to generate
+// new passwords by Rotate
+type Policy struct {
+	// Length of generated passwords, which is also the minimum length
+	Length int `json:",omitempty"`
+
+	// MaxLength limits length of passwords, 0 means no limit
+	MaxLength int `json:",omitempty"`
+
+	// Classes required, composed of d(digit), c(lowercase), C(uppercase) and s(special)
+	Classes string `json:",omitempty"`
+
+	// Forbidden characters
+	Forbidden string `json:",omitempty"`
+
+	// Pattern generates passwords by GeneratePattern if it's not empty
+	Pattern string `json:",omitempty"`
+}
+
+// DefaultPolicy is used to rotate passwords which have no policy
+var DefaultPolicy = Policy{Length: 20, Classes: "dcCs"}
+
+// ParsePolicy parses policy from key-value pairs, see Policy* for keys
+func ParsePolicy(values map[string]string) (*Policy, error) {
+	policy := new(Policy)
+	for key, value := range values {
+		var err error
+		switch key {
+		case PolicyLength:
+			policy.Length, err = strconv.Atoi(value)
+		case PolicyMaxLength:
+			policy.MaxLength, err = strconv.Atoi(value)
+		case PolicyClasses:
+			policy.Classes = value
+		case PolicyForbidden:
+			policy.Forbidden = value
+		case PolicyPattern:
+			policy.Pattern = value
+		default:
+			return nil, fmt.Errorf("unknown policy %s, must be one of %s", key, strings.Join([]string{
+				PolicyLength, PolicyMaxLength, PolicyClasses, PolicyForbidden, PolicyPattern,
+			}, ","))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy %s: %q", key, value)
+		}
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Validate checks whether the policy can be satisfied
+func (policy *Policy) Validate() error {
+	if policy.Length < 0 || policy.MaxLength < 0 {
+		return fmt.Errorf("length of policy must not be negative")
+	}
+	if policy.MaxLength > 0 && policy.Length > policy.MaxLength {
+		return fmt.Errorf("length %d of policy exceeds max length %d", policy.Length, policy.MaxLength)
+	}
+	for i := 0; i < len(policy.Classes); i++ {
+		switch policy.Classes[i] {
+		case ClassDigit, ClassLower, ClassUpper, ClassSpecial:
+		default:
+			return fmt.Errorf("unknown class %c of policy, must be one of d,c,C,s", policy.Classes[i])
+		}
+	}
+	if policy.MaxLength > 0 && policy.MaxLength < len(removeChars(policy.Classes, "")) {
+		return fmt.Errorf("max length %d of policy is less than number of classes", policy.MaxLength)
+	}
+	return nil
+}
+
+func (policy *Policy) has(class byte) bool {
+	return strings.IndexByte(policy.Classes, class) >= 0
+}
+
+// generator returns generator of the policy
+func (policy *Policy) generator() Generator {
+	return Generator{
+		Digit:   policy.has(ClassDigit),
+		Lower:   policy.has(ClassLower),
+		Upper:   policy.has(ClassUpper),
+		Special: policy.has(ClassSpecial),
+		Exclude: policy.Forbidden,
+	}
+}
+
+// Generate generates a password which satisfies the policy
+func (policy *Policy) Generate() (string, error) {
+	var (
+		password string
+		err      error
+		g        = policy.generator()
+	)
+	if policy.Pattern != "" {
+		password, err = g.GeneratePattern(policy.Pattern)
+	} else {
+		n := policy.Length
+		if n == 0 {
+			n = DefaultPolicy.Length
+		}
+		if policy.MaxLength > 0 && n > policy.MaxLength {
+			n = policy.MaxLength
+		}
+		password, err = g.Generate(n)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := policy.Check(password); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+// Check checks whether the password satisfies the policy
+func (policy *Policy) Check(password string) error {
+	if len(password) < policy.Length {
+		return fmt.Errorf("password is shorter than %d", policy.Length)
+	}
+	if policy.MaxLength > 0 && len(password) > policy.MaxLength {
+		return fmt.Errorf("password is longer than %d", policy.MaxLength)
+	}
+	if i := strings.IndexAny(password, policy.Forbidden); policy.Forbidden != "" && i >= 0 {
+		return fmt.Errorf("password contains forbidden character %c", password[i])
+	}
+	for _, c := range []struct {
+		class byte
+		name  string
+		chars string
+	}{
+		{ClassDigit, "digit", DigitChars},
+		{ClassLower, "lowercase", LowerChars},
+		{ClassUpper, "uppercase", UpperChars},
+	} {
+		if policy.has(c.class) && !strings.ContainsAny(password, c.chars) {
+			return fmt.Errorf("password contains no %s character", c.name)
+		}
+	}
+	if policy.has(ClassSpecial) && strings.Trim(password, DigitChars+LowerChars+UpperChars) == "" {
+		return fmt.Errorf("password contains no special character")
+	}
+	return nil
+}
+
+// String returns policy as comma separated key=value pairs
+func (policy *Policy) String() string {
+	var pairs []string
+	for _, kv := range [][2]string{
+		{PolicyLength, strconv.Itoa(policy.Length)},
+		{PolicyMaxLength, strconv.Itoa(policy.MaxLength)},
+		{PolicyClasses, policy.Classes},
+		{PolicyForbidden, policy.Forbidden},
+		{PolicyPattern, policy.Pattern},
+	} {
+		if kv[1] != "" && kv[1] != "0" {
+			pairs = append(pairs, kv[0]+"="+kv[1])
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// FindOne finds the only password by id, alias or word like Find, and returns it's id
+func (box *Box) FindOne(word string) (string, error) {
+	box.RLock()
+	defer box.RUnlock()
+	if box.masterPassword == "" {
+		return "", errEmptyMasterPassword
+	}
+	passwords, err := box.findPasswords([]string{word}, true)
+	if err != nil && !strings.HasPrefix(word, AliasPrefix) {
+		passwords, err = box.query(word)
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(passwords) > 1 {
+		return "", newErrAmbiguous(passwords)
+	}
+	return passwords[0].ID, nil
+}
+
+var stalePasswordHeader = []string{"ID", "CATEGORY", "ACCOUNT", "SITE", "UPDATED_AT"}
+
+// OlderThan writes passwords which were not updated within specified duration to
+// specified writer, and returns ids of these passwords, the least recently updated first.
+// Passwords which can't be rotated, e.g. cards and ssh keys, are excluded.
+func (box *Box) OlderThan(w io.Writer, d time.Duration) ([]string, error) {
+	box.RLock()
+	defer box.RUnlock()
+	if box.masterPassword == "" {
+		return nil, errEmptyMasterPassword
+	}
+	before := time.Now().Add(-d).Unix()
+	passwords := box.find(func(pw *Password) bool {
+		return pw.LastUpdatedAt < before && box.checkRotatable(pw) == nil
+	})
+	sort.Slice(passwords, func(i, j int) bool {
+		if passwords[i].LastUpdatedAt != passwords[j].LastUpdatedAt {
+			return passwords[i].LastUpdatedAt < passwords[j].LastUpdatedAt
+		}
+		return passwords[i].ID < passwords[j].ID
+	})
+	ids := make([]string, 0, len(passwords))
+	var table textutil.StringMatrix
+	for _, pw := range passwords {
+		ids = append(ids, pw.ID)
+		table = append(table, []string{
+			pw.ShortID(),
+			pw.Category,
+			shorten(pw.PlainAccount, 32),
+			shorten(pw.Site, 32),
+			time.Unix(pw.LastUpdatedAt, 0).Format(time.RFC3339),
+		})
+	}
+	if len(passwords) > 0 {
+		textutil.WriteTable(w, textutil.AddTableHeader(table, stalePasswordHeader), box.colorID(w, true))
+	}
+	return ids, nil
+}
+
+// checkRotatable checks whether password can be replaced by a generated one
+func (box *Box) checkRotatable(pw *Password) error {
+	kind, err := box.lookupKind(pw.Kind)
+	if err != nil {
+		return err
+	}
+	if spec, ok := kind.Field(FieldPassword); !ok || spec.Type != FieldText {
+		return fmt.Errorf("password of kind %s can't be rotated", kind.Name)
+	}
+	if IsReference(pw.PlainPassword) {
+		return fmt.Errorf("password %s refers to another password, rotate the referred one", pw.ShortID())
+	}
+	return nil
+}
+
+// Rotate replaces passwords by ids with new passwords generated by their policies,
+// or DefaultPolicy if no policy specified. Old passwords are kept as history.
+// Counters of derived passwords are increased instead. It returns new passwords by id.
+func (box *Box) Rotate(ids []string) (map[string]string, error) {
+	box.Lock()
+	defer box.Unlock()
+	if box.masterPassword == "" {
+		return nil, errEmptyMasterPassword
+	}
+	passwords, err := box.findPasswords(ids, false)
+	if err != nil {
+		return nil, err
+	}
+	generated := make(map[string]string, len(passwords))
+	for _, pw := range passwords {
+		if err := box.checkRotatable(pw); err != nil {
+			return nil, err
+		}
+		if pw.Derived != nil {
+			d := *pw.Derived
+			d.Counter++
+			password, err := DerivePassword(box.masterPassword, pw.Site, pw.PlainAccount, d)
+			if err != nil {
+				return nil, fmt.Errorf("derive password of %s: %v", pw.ShortID(), err)
+			}
+			generated[pw.ID] = password
+			continue
+		}
+		policy := pw.Policy
+		if policy == nil {
+			policy = &DefaultPolicy
+		}
+		password, err := policy.Generate()
+		if err != nil {
+			return nil, fmt.Errorf("generate password of %s: %v", pw.ShortID(), err)
+		}
+		generated[pw.ID] = password
+	}
+	now := time.Now().Unix()
+	for _, pw := range passwords {
+		pw.LastUpdatedAt = now
+		if pw.Derived != nil {
+			pw.Derived.Counter++
+			continue
+		}
+		old := pw.PlainPassword
+		pw.PlainPassword = generated[pw.ID]
+		pw.pushHistory(old, now)
+	}
+	if len(passwords) == 0 {
+		return generated, nil
+	}
+	return generated, box.save()
+}
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	for i, tc := range []struct {
+		values map[string]string
+		want   string
+		ok     bool
+	}{
+		{map[string]string{"length": "16", "classes": "dcC"}, "length=16,classes=dcC", true},
+		{map[string]string{"max-length": "12", "forbidden": "<>&"}, "max-length=12,forbidden=<>&", true},
+		{map[string]string{"pattern": "Aaaa-9999"}, "pattern=Aaaa-9999", true},
+		{map[string]string{"length": "x"}, "", false},
+		{map[string]string{"length": "20", "max-length": "12"}, "", false},
+		{map[string]string{"classes": "dx"}, "", false},
+		{map[string]string{"unknown": "1"}, "", false},
+	} {
+		policy, err := ParsePolicy(tc.values)
+		if (err == nil) != tc.ok {
+			t.Errorf("%dth: want ok %v, got error %v", i, tc.ok, err)
+			continue
+		}
+		if err == nil && policy.String() != tc.want {
+			t.Errorf("%dth: want %s, got %s", i, tc.want, policy.String())
+		}
+	}
+}
+
+func TestPolicyGenerate(t *testing.T) {
+	for i, policy := range []Policy{
+		DefaultPolicy,
+		{MaxLength: 12, Classes: "dC"},
+		{Length: 16, Classes: "dcCs", Forbidden: "<>&'\"%"},
+		{Classes: "d", Pattern: "99-99-99"},
+	} {
+		for j := 0; j < 20; j++ {
+			password, err := policy.Generate()
+			if err != nil {
+				t.Fatalf("%dth: Generate error: %v", i, err)
+			}
+			if err := policy.Check(password); err != nil {
+				t.Errorf("%dth: Check %q error: %v", i, password, err)
+			}
+			if policy.Forbidden != "" && strings.ContainsAny(password, policy.Forbidden) {
+				t.Errorf("%dth: %q contains forbidden characters", i, password)
+			}
+		}
+	}
+	policy := Policy{Classes: "s", Pattern: "aaaa"}
+	if _, err := policy.Generate(); err == nil {
+		t.Errorf("Generate pattern without special character want error, got nil")
+	}
+}
+
+func TestRotate(t *testing.T) {
+	box := NewBox(NewMemRepository([]byte{}))
+	box.masterPassword = "123456"
+	box.passwords = map[string]*Password{}
+
+	pw := NewPassword("bank", "alice", "password", "bank.example.com")
+	pw.Policy = &Policy{Length: 8, MaxLength: 8, Classes: "d", Forbidden: "0"}
+	id, _, err := box.Add(pw)
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	old := NewPassword("mail", "alice", "password", "mail.example.com")
+	oldID, _, err := box.Add(old)
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	box.passwords[oldID].LastUpdatedAt = time.Now().Add(-200 * 24 * time.Hour).Unix()
+	card := NewEmptyPassword()
+	card.Kind = "card"
+	card.PlainPassword = "4111111111111111"
+	card.SetField("expiry", "01/30")
+	cardID, _, err := box.Add(card)
+	if err != nil {
+		t.Fatalf("Add card error: %v", err)
+	}
+	box.passwords[cardID].LastUpdatedAt = 0
+
+	if found, err := box.FindOne("bank"); err != nil || found != id {
+		t.Errorf("FindOne bank want %s, got %s, %v", id, found, err)
+	}
+	if _, err := box.FindOne("alice"); err == nil {
+		t.Errorf("FindOne alice want ambiguous error, got nil")
+	}
+
+	generated, err := box.Rotate([]string{id})
+	if err != nil {
+		t.Fatalf("Rotate error: %v", err)
+	}
+	got := box.passwords[id]
+	if got.PlainPassword != generated[id] || len(got.PlainPassword) != 8 || strings.Trim(got.PlainPassword, "123456789") != "" {
+		t.Errorf("Rotate want 8 digits without 0, got %q", got.PlainPassword)
+	}
+	if len(got.History) != 1 || got.History[0].Password != "password" {
+		t.Errorf("Rotate want old password in history, got %v", got.History)
+	}
+	if _, err := box.Rotate([]string{cardID}); err == nil {
+		t.Errorf("Rotate card want error, got nil")
+	}
+
+	var buf bytes.Buffer
+	ids, err := box.OlderThan(&buf, 180*24*time.Hour)
+	if err != nil {
+		t.Fatalf("OlderThan error: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != oldID {
+		t.Errorf("OlderThan want [%s], got %v", oldID, ids)
+	}
+}
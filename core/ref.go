@@ -0,0 +1,185 @@
+package core
+
+import (
+	"regexp"
+)
+
+// refPattern matches reference to field of another password, e.g. {ref:3439d31.password}
+var refPattern = regexp.MustCompile(`\{ref:([0-9a-fA-F]+)\.([^{}.\s]+)\}`)
+
+// maxRefDepth limits length of reference chains
+const maxRefDepth = 8
+
+// IsReference reports whether value contains references to fields of other passwords
+func IsReference(value string) bool {
+	return refPattern.MatchString(value)
+}
+
+// refValues returns values of password which may contain references
+func (pw *Password) refValues() []string {
+	values := []string{pw.PlainAccount, pw.PlainPassword, pw.Site}
+	for _, field := range pw.Fields {
+		values = append(values, field.Value)
+	}
+	return values
+}
+
+// references reports whether password references the password with id, short
+// ids of references are resolved like resolveValue
+func (box *Box) references(pw *Password, id string) bool {
+	for _, value := range pw.refValues() {
+		for _, m := range refPattern.FindAllStringSubmatch(value, -1) {
+			if passwords, err := box.findPasswords([]string{m[1]}, false); err == nil && passwords[0].ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveValue replaces references in value, visited contains ids of passwords
+// on the reference chain
+func (box *Box) resolveValue(value string, visited []string) (string, error) {
+	var rerr error
+	resolved := refPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		if rerr != nil {
+			return ""
+		}
+		m := refPattern.FindStringSubmatch(ref)
+		passwords, err := box.findPasswords([]string{m[1]}, false)
+		if err != nil {
+			rerr = newErrInvalidReference(ref, err.Error())
+			return ""
+		}
+		target := passwords[0]
+		if stringsContains(visited, target.ID) {
+			rerr = newErrInvalidReference(ref, "circular reference")
+			return ""
+		}
+		if len(visited) >= maxRefDepth {
+			rerr = newErrInvalidReference(ref, "too many nested references")
+			return ""
+		}
+		v := target.GetField(m[2])
+		if m[2] == fieldPassword && target.Derived != nil {
+			var err error
+			if v, err = box.derive(target); err != nil {
+				rerr = newErrInvalidReference(ref, err.Error())
+				return ""
+			}
+		}
+		if v == "" {
+			rerr = newErrInvalidReference(ref, "field "+m[2]+" is empty")
+			return ""
+		}
+		v, rerr = box.resolveValue(v, append(visited[:len(visited):len(visited)], target.ID))
+		return v
+	})
+	return resolved, rerr
+}
+
+// resolve returns a copy of password whose references are replaced with values
+// of referenced fields
+func (box *Box) resolve(pw *Password) (*Password, error) {
+	resolved := *pw
+	visited := []string{pw.ID}
+	var err error
+	if resolved.PlainAccount, err = box.resolveValue(pw.PlainAccount, visited); err != nil {
+		return nil, err
+	}
+	if resolved.PlainPassword, err = box.resolveValue(pw.PlainPassword, visited); err != nil {
+		return nil, err
+	}
+	if resolved.Site, err = box.resolveValue(pw.Site, visited); err != nil {
+		return nil, err
+	}
+	if pw.Derived != nil {
+		if resolved.PlainPassword, err = box.derive(&resolved); err != nil {
+			return nil, err
+		}
+	}
+	resolved.Fields = make([]Field, len(pw.Fields))
+	for i, field := range pw.Fields {
+		if field.Value, err = box.resolveValue(field.Value, visited); err != nil {
+			return nil, err
+		}
+		resolved.Fields[i] = field
+	}
+	return &resolved, nil
+}
+
+// resolveAll resolves references of passwords, a password whose references can't
+// be resolved is kept as is and marked broken, so it can still be shown and fixed
+func (box *Box) resolveAll(passwords []*Password) []*Password {
+	resolved := make([]*Password, 0, len(passwords))
+	for _, pw := range passwords {
+		r, err := box.resolve(pw)
+		if err != nil {
+			r = pw.clone()
+			r.broken = err
+		}
+		resolved = append(resolved, r)
+	}
+	return resolved
+}
+
+// Referrers returns ids of passwords which reference the password with id
+func (box *Box) Referrers(id string) []string {
+	box.RLock()
+	defer box.RUnlock()
+	return box.referrers(id)
+}
+
+func (box *Box) referrers(id string) []string {
+	ids := []string{}
+	for _, pw := range box.find(func(pw *Password) bool {
+		return pw.ID != id && box.references(pw, id)
+	}) {
+		ids = append(ids, pw.ID)
+	}
+	return ids
+}
+
+// redirectReferences replaces references to the password with id from by
+// references to the password with id to, e.g. when from is merged into to
+func (box *Box) redirectReferences(from, to string) {
+	redirect := func(value string) string {
+		return refPattern.ReplaceAllStringFunc(value, func(ref string) string {
+			m := refPattern.FindStringSubmatch(ref)
+			if passwords, err := box.findPasswords([]string{m[1]}, false); err != nil || passwords[0].ID != from {
+				return ref
+			}
+			return "{ref:" + to + "." + m[2] + "}"
+		})
+	}
+	for _, id := range box.referrers(from) {
+		pw := box.passwords[id]
+		pw.PlainAccount = redirect(pw.PlainAccount)
+		pw.PlainPassword = redirect(pw.PlainPassword)
+		pw.Site = redirect(pw.Site)
+		for i := range pw.Fields {
+			pw.Fields[i].Value = redirect(pw.Fields[i].Value)
+		}
+	}
+}
+
+// checkReferrers returns error if any of passwords to be removed is referenced
+// by a password which is not removed, whose references would be broken
+func (box *Box) checkReferrers(passwords []*Password) error {
+	removing := map[string]bool{}
+	for _, pw := range passwords {
+		removing[pw.ID] = true
+	}
+	for _, pw := range passwords {
+		var ids []string
+		for _, id := range box.referrers(pw.ID) {
+			if !removing[id] {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) > 0 {
+			return newErrReferenced(pw.ID, ids)
+		}
+	}
+	return nil
+}
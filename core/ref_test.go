@@ -0,0 +1,93 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReferences(t *testing.T) {
+	box := NewBox(NewMemRepository([]byte{}))
+	box.masterPassword = "123456"
+	box.passwords = map[string]*Password{
+		"3439d31": NewPassword("sso", "alice", "sso-secret", ""),
+		"1234567": NewPassword("tools", "{ref:3439d31.account}", "{ref:3439d31.password}", ""),
+		"1234568": NewPassword("tools", "bob", "{ref:1234567.password}", ""),
+	}
+	for id, pw := range box.passwords {
+		pw.ID = id
+	}
+
+	var buf bytes.Buffer
+	if err := box.Find(&buf, "bob", true, true, SortByID); err != nil {
+		t.Fatalf("Find error: %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != "sso-secret" {
+		t.Errorf("Find nested reference want sso-secret, got %q", got)
+	}
+	if got := box.passwords["1234568"].PlainPassword; got != "{ref:1234567.password}" {
+		t.Errorf("stored value want unresolved reference, got %q", got)
+	}
+
+	box.passwords["3439d31"].PlainPassword = "new-secret"
+	resolved, err := box.resolve(box.passwords["1234567"])
+	if err != nil || resolved.PlainPassword != "new-secret" || resolved.PlainAccount != "alice" {
+		t.Errorf("resolve want alice/new-secret, got %v, %v", resolved, err)
+	}
+
+	if got := box.Referrers("3439d31"); !stringsEqual(got, []string{"1234567"}) {
+		t.Errorf("Referrers want [1234567], got %v", got)
+	}
+
+	for i, value := range []string{
+		"{ref:fffffff.password}", // not found
+		"{ref:1234569.password}", // circular
+		"{ref:3439d31.site}",     // empty field
+	} {
+		pw := NewPassword("x", "y", value, "")
+		pw.ID = "1234569"
+		box.passwords[pw.ID] = pw
+		if _, err := box.resolve(pw); err == nil {
+			t.Errorf("%dth: resolve %s want error, got nil", i, value)
+		}
+		delete(box.passwords, pw.ID)
+	}
+}
+
+func TestBrokenReferences(t *testing.T) {
+	box := NewBox(NewMemRepository([]byte{}))
+	box.masterPassword = "123456"
+	box.passwords = map[string]*Password{
+		"3439d31": NewPassword("sso", "alice", "sso-secret", ""),
+		"3439d32": NewPassword("sso", "carol", "other-secret", ""),
+		"1234567": NewPassword("tools", "bob", "{ref:3439d31.password}", ""),
+	}
+	for id, pw := range box.passwords {
+		pw.ID = id
+	}
+
+	if box.references(box.passwords["1234567"], "3439d32") {
+		t.Errorf("references want false for an id sharing the prefix")
+	}
+	if _, err := box.Remove([]string{"3439d31"}, false, true, false); err == nil {
+		t.Errorf("Remove referenced password without force want error, got nil")
+	}
+	if _, err := box.Remove([]string{"3439d31"}, false, true, true); err != nil {
+		t.Fatalf("Remove with force error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := box.Find(&buf, "", false, false, SortByID); err != nil {
+		t.Fatalf("Find with broken reference error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "3439d32") || !strings.Contains(buf.String(), "(broken)") {
+		t.Errorf("Find want other entries and a broken marker, got:\n%s", buf.String())
+	}
+	buf.Reset()
+	if err := box.Inspect(&buf, []string{"1234567"}, false); err != nil {
+		t.Fatalf("Inspect with broken reference error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Broken") {
+		t.Errorf("Inspect want broken reason, got:\n%s", buf.String())
+	}
+}
@@ -21,7 +21,7 @@ func (repo *fileRepository) Load() ([]byte, error) {
 
 // Save implements BoxRepository.Save method
 func (repo *fileRepository) Save(data []byte) error {
-	return ioutil.WriteFile(repo.filename, data, 0666)
+	return ioutil.WriteFile(repo.filename, data, 0600)
 }
 
 // memRepository implements BoxRepository interface
@@ -0,0 +1,232 @@
+package core
+
+import (
+	"fmt"
+	"math"
+)
+
+// maxStrengthLength limits length of password to estimate, the rest is ignored
+const maxStrengthLength = 256
+
+// RecommendedScore is the minimum score of strong passwords
+const RecommendedScore = 3
+
+// crackRate is number of guesses per second of an offline attack against a slow
+// hash, e.g. scrypt used by onepw
+const crackRate = 1e4
+
+// Strength is estimated strength of password in the spirit of zxcvbn
+type Strength struct {
+	// Log10 of estimated number of guesses to crack the password
+	GuessesLog10 float64
+
+	// Score from 0(too guessable) to 4(very unguessable)
+	Score int
+
+	// Warning explains why the password is weak, empty if it's strong or no
+	// pattern found
+	Warning string
+}
+
+// estimation is the most guessable sequence of matches of password
+type estimation struct {
+	log10    float64
+	sequence []match
+}
+
+// EstimateStrength estimates strength of password by the most guessable sequence
+// of dictionary words(common passwords, english words and user inputs, e.g.
+// account and site), reversed words, l33t substitutions, keyboard walks,
+// repeats, sequences and dates, characters matched by none are brute forced
+func EstimateStrength(password string, userInputs ...string) Strength {
+	if len(password) > maxStrengthLength {
+		password = password[:maxStrengthLength]
+	}
+	e := estimate(password, dictionaries(userInputs))
+	s := Strength{GuessesLog10: e.log10}
+	switch {
+	case e.log10 < 3:
+		s.Score = 0
+	case e.log10 < 6:
+		s.Score = 1
+	case e.log10 < 8:
+		s.Score = 2
+	case e.log10 < 10:
+		s.Score = 3
+	default:
+		s.Score = 4
+	}
+	if s.Score < RecommendedScore {
+		s.Warning = warning(e.sequence)
+	}
+	return s
+}
+
+// estimate finds the most guessable sequence of matches by dynamic programming,
+// guesses of the sequence is product of guesses of matches
+func estimate(password string, dicts []dictionary) estimation {
+	n := len(password)
+	if n == 0 {
+		return estimation{}
+	}
+	byEnd := make([][]match, n)
+	for _, m := range allMatches(password, dicts) {
+		byEnd[m.j] = append(byEnd[m.j], m)
+	}
+	bruteforce := math.Log10(bruteforceCardinality(password))
+	best := make([]float64, n+1)
+	prev := make([]*match, n+1)
+	for k := 1; k <= n; k++ {
+		best[k] = best[k-1] + bruteforce
+		for x := range byEnd[k-1] {
+			m := &byEnd[k-1][x]
+			minGuesses := 10.0
+			if len(m.token) > 1 {
+				minGuesses = 50
+			}
+			if cost := best[m.i] + math.Log10(math.Max(m.guesses, minGuesses)); cost < best[k] {
+				best[k], prev[k] = cost, m
+			}
+		}
+	}
+	e := estimation{log10: best[n]}
+	for k := n; k > 0; {
+		if prev[k] == nil {
+			k--
+			continue
+		}
+		e.sequence = append([]match{*prev[k]}, e.sequence...)
+		k = prev[k].i
+	}
+	return e
+}
+
+// bruteforceCardinality returns size of character set used by password
+func bruteforceCardinality(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	for i := 0; i < len(password); i++ {
+		switch c := password[i]; {
+		case 'a' <= c && c <= 'z':
+			lower = true
+		case 'A' <= c && c <= 'Z':
+			upper = true
+		case '0' <= c && c <= '9':
+			digit = true
+		case c < 0x80:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+	cardinality := 0.0
+	for _, c := range []struct {
+		used bool
+		size float64
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if c.used {
+			cardinality += c.size
+		}
+	}
+	return math.Max(cardinality, 10)
+}
+
+// warning returns warning of the longest match of sequence
+func warning(sequence []match) string {
+	if len(sequence) == 0 {
+		return ""
+	}
+	longest := sequence[0]
+	for _, m := range sequence[1:] {
+		if len(m.token) > len(longest.token) {
+			longest = m
+		}
+	}
+	sole := len(sequence) == 1
+	switch longest.pattern {
+	case patternDictionary:
+		switch {
+		case longest.dictionary == "user_inputs":
+			return "Passwords containing the account, category or site are easy to guess"
+		case longest.dictionary == "passwords" && sole && !longest.l33t && !longest.reversed:
+			if longest.rank <= 10 {
+				return "This is a top-10 common password"
+			} else if longest.rank <= 100 {
+				return "This is a top-100 common password"
+			}
+			return "This is a very common password"
+		case longest.dictionary == "passwords":
+			return "This is similar to a commonly used password"
+		case longest.l33t:
+			return "Predictable substitutions like '@' instead of 'a' don't help very much"
+		case longest.reversed:
+			return "Reversed words aren't much harder to guess"
+		case sole:
+			return "A word by itself is easy to guess"
+		}
+	case patternSpatial:
+		if longest.turns == 1 {
+			return "Straight rows of keys are easy to guess"
+		}
+		return "Short keyboard patterns are easy to guess"
+	case patternRepeat:
+		if len(longest.base) == 1 {
+			return "Repeats like \"aaa\" are easy to guess"
+		}
+		return "Repeats like \"abcabcabc\" are only slightly harder to guess than \"abc\""
+	case patternSequence:
+		return "Sequences like abc or 6543 are easy to guess"
+	case patternDate:
+		return "Dates are often easy to guess"
+	case patternYear:
+		return "Recent years are easy to guess"
+	}
+	return ""
+}
+
+// CrackTime returns estimated time to crack the password by an offline attack
+// against the slow hash of onepw, e.g. "3 hours" or "centuries"
+func (s Strength) CrackTime() string {
+	seconds := math.Pow(10, s.GuessesLog10) / crackRate
+	const (
+		minute  = 60
+		hour    = minute * 60
+		day     = hour * 24
+		month   = day * 31
+		year    = month * 12
+		century = year * 100
+	)
+	for _, unit := range []struct {
+		size float64
+		name string
+	}{{year, "year"}, {month, "month"}, {day, "day"}, {hour, "hour"}, {minute, "minute"}, {1, "second"}} {
+		if seconds >= century {
+			return "centuries"
+		}
+		if seconds < unit.size {
+			continue
+		}
+		n := int(math.Round(seconds / unit.size))
+		if n == 1 {
+			return "1 " + unit.name
+		}
+		return fmt.Sprintf("%d %ss", n, unit.name)
+	}
+	return "less than a second"
+}
+
+// String returns score, crack time and warning of the strength
+func (s Strength) String() string {
+	text := fmt.Sprintf("score %d/4, cracked in %s", s.Score, s.CrackTime())
+	if s.Warning != "" {
+		text += ": " + s.Warning
+	}
+	return text
+}
+
+// CheckStrength checks whether score of the password is at least minScore
+func CheckStrength(password string, minScore int, userInputs ...string) error {
+	if s := EstimateStrength(password, userInputs...); s.Score < minScore {
+		return newErrWeakPassword(s, minScore)
+	}
+	return nil
+}
@@ -0,0 +1,584 @@
+package core
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Patterns of matches
+const (
+	patternDictionary = "dictionary"
+	patternSpatial    = "spatial"
+	patternRepeat     = "repeat"
+	patternSequence   = "sequence"
+	patternDate       = "date"
+	patternYear       = "year"
+)
+
+// match is a guessable substring password[i:j+1] of password
+type match struct {
+	pattern string
+	i, j    int
+	token   string
+	guesses float64
+
+	// dictionary
+	dictionary string
+	rank       int
+	reversed   bool
+	l33t       bool
+
+	// spatial
+	turns int
+
+	// repeat
+	base string
+}
+
+// dictionary ranks words, the most common word has rank 1
+type dictionary struct {
+	name  string
+	ranks map[string]int
+}
+
+func newDictionary(name string, words []string) dictionary {
+	d := dictionary{name: name, ranks: make(map[string]int, len(words))}
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if _, ok := d.ranks[word]; !ok && word != "" {
+			d.ranks[word] = i + 1
+		}
+	}
+	return d
+}
+
+var (
+	builtinDictionariesOnce sync.Once
+	builtinDictionaries     []dictionary
+)
+
+func dictionaries(userInputs []string) []dictionary {
+	builtinDictionariesOnce.Do(func() {
+		builtinDictionaries = []dictionary{
+			newDictionary("passwords", commonPasswords),
+			newDictionary("english", defaultWords),
+		}
+	})
+	var words []string
+	for _, input := range userInputs {
+		words = append(words, input)
+		for _, part := range strings.FieldsFunc(input, func(r rune) bool {
+			return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+		}) {
+			if len(part) >= 3 {
+				words = append(words, part)
+			}
+		}
+	}
+	if len(words) == 0 {
+		return builtinDictionaries
+	}
+	return append([]dictionary{newDictionary("user_inputs", words)}, builtinDictionaries...)
+}
+
+// allMatches returns matches of all patterns in password
+func allMatches(password string, dicts []dictionary) []match {
+	var matches []match
+	matches = append(matches, dictionaryMatch(password, dicts)...)
+	matches = append(matches, reverseDictionaryMatch(password, dicts)...)
+	matches = append(matches, l33tMatch(password, dicts)...)
+	matches = append(matches, spatialMatch(password)...)
+	matches = append(matches, repeatMatch(password, dicts)...)
+	matches = append(matches, sequenceMatch(password)...)
+	matches = append(matches, dateMatch(password)...)
+	return matches
+}
+
+//------------
+// dictionary
+//------------
+
+func dictionaryMatch(password string, dicts []dictionary) []match {
+	var matches []match
+	lower := strings.ToLower(password)
+	for _, d := range dicts {
+		for i := 0; i < len(lower); i++ {
+			for j := i; j < len(lower); j++ {
+				rank, ok := d.ranks[lower[i:j+1]]
+				if !ok {
+					continue
+				}
+				m := match{pattern: patternDictionary, i: i, j: j, token: password[i : j+1], dictionary: d.name, rank: rank}
+				m.guesses = float64(rank) * uppercaseVariations(m.token)
+				matches = append(matches, m)
+			}
+		}
+	}
+	return matches
+}
+
+func reverseDictionaryMatch(password string, dicts []dictionary) []match {
+	n := len(password)
+	reversed := []byte(password)
+	for i, j := 0, n-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+	matches := dictionaryMatch(string(reversed), dicts)
+	for k := range matches {
+		m := &matches[k]
+		m.i, m.j = n-1-m.j, n-1-m.i
+		m.token = password[m.i : m.j+1]
+		m.reversed = true
+		m.guesses *= 2
+	}
+	return matches
+}
+
+// uppercaseVariations returns number of ways to capitalize the word
+func uppercaseVariations(word string) float64 {
+	upper, lower := 0, 0
+	for i := 0; i < len(word); i++ {
+		switch c := word[i]; {
+		case 'A' <= c && c <= 'Z':
+			upper++
+		case 'a' <= c && c <= 'z':
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 1
+	}
+	if lower == 0 {
+		return 2
+	}
+	isUpper := func(c byte) bool { return 'A' <= c && c <= 'Z' }
+	if upper == 1 && (isUpper(word[0]) || isUpper(word[len(word)-1])) {
+		return 2
+	}
+	return sumBinomials(upper, lower)
+}
+
+// sumBinomials returns sum of C(s+u, i) for i in [1, min(s,u)]
+func sumBinomials(s, u int) float64 {
+	sum := 0.0
+	for i := 1; i <= s && i <= u; i++ {
+		sum += binomial(s+u, i)
+	}
+	return sum
+}
+
+func binomial(n, k int) float64 {
+	if k > n {
+		return 0
+	}
+	r := 1.0
+	for d := 1; d <= k; d++ {
+		r = r * float64(n-k+d) / float64(d)
+	}
+	return r
+}
+
+//------
+// l33t
+//------
+
+// l33tTable maps letters to their l33t substitutions
+var l33tTable = map[byte]string{
+	'a': "4@", 'b': "8", 'c': "({[<", 'e': "3", 'g': "69", 'i': "1!|",
+	'l': "1|7", 'o': "0", 's': "$5", 't': "+7", 'x': "%", 'z': "2",
+}
+
+// maxL33tSubstitutions limits number of substitution tables tried
+const maxL33tSubstitutions = 64
+
+// l33tSubstitutions returns possible tables which map substituted characters
+// in password to letters
+func l33tSubstitutions(password string) []map[byte]byte {
+	letters := map[byte][]byte{}
+	for letter, subs := range l33tTable {
+		for i := 0; i < len(subs); i++ {
+			if strings.IndexByte(password, subs[i]) >= 0 {
+				letters[subs[i]] = append(letters[subs[i]], letter)
+			}
+		}
+	}
+	var subs []byte
+	for sub := range letters {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i] < subs[j] })
+	tables := []map[byte]byte{{}}
+	for _, sub := range subs {
+		candidates := letters[sub]
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i] < candidates[j] })
+		var next []map[byte]byte
+		for _, table := range tables {
+			for _, letter := range candidates {
+				if len(next) == maxL33tSubstitutions {
+					break
+				}
+				t := make(map[byte]byte, len(table)+1)
+				for k, v := range table {
+					t[k] = v
+				}
+				t[sub] = letter
+				next = append(next, t)
+			}
+		}
+		tables = next
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+	return tables
+}
+
+func l33tMatch(password string, dicts []dictionary) []match {
+	var matches []match
+	lower := strings.ToLower(password)
+	for _, table := range l33tSubstitutions(lower) {
+		translated := []byte(lower)
+		for i := range translated {
+			if letter, ok := table[translated[i]]; ok {
+				translated[i] = letter
+			}
+		}
+		for _, m := range dictionaryMatch(string(translated), dicts) {
+			if m.i == m.j || string(translated[m.i:m.j+1]) == lower[m.i:m.j+1] {
+				continue
+			}
+			m.token = password[m.i : m.j+1]
+			m.l33t = true
+			m.guesses = float64(m.rank) * uppercaseVariations(m.token) * l33tVariations(lower[m.i:m.j+1], table)
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// l33tVariations returns number of ways to substitute letters of the token
+func l33tVariations(token string, table map[byte]byte) float64 {
+	variations := 1.0
+	for sub, letter := range table {
+		s, u := strings.Count(token, string(sub)), strings.Count(token, string(letter))
+		if s == 0 {
+			continue
+		}
+		if u == 0 {
+			variations *= 2
+		} else {
+			variations *= sumBinomials(s, u)
+		}
+	}
+	return variations
+}
+
+//---------
+// spatial
+//---------
+
+// keyboardRow is a row of keyboard, see qwertyRows
+type keyboardRow struct {
+	offset int
+	keys   string
+}
+
+// keyboard is an adjacency graph of keys
+type keyboard struct {
+	// keys by character
+	keys map[byte]string
+	// neighbors of key by direction, empty if no neighbor at the direction
+	neighbors map[string][]string
+	// number of keys and average number of neighbors
+	starts int
+	degree float64
+}
+
+func newKeyboard(rows []keyboardRow, slanted bool) *keyboard {
+	positions := map[[2]int]string{}
+	for y, row := range rows {
+		for k := 0; k+1 < len(row.keys); k += 2 {
+			positions[[2]int{row.offset + k/2, y}] = row.keys[k : k+2]
+		}
+	}
+	directions := [][2]int{{-1, 0}, {-1, -1}, {0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}}
+	if slanted {
+		directions = [][2]int{{-1, 0}, {0, -1}, {1, -1}, {1, 0}, {0, 1}, {-1, 1}}
+	}
+	kb := &keyboard{keys: map[byte]string{}, neighbors: map[string][]string{}, starts: len(positions)}
+	total := 0
+	for p, key := range positions {
+		neighbors := make([]string, len(directions))
+		for d, dir := range directions {
+			if n, ok := positions[[2]int{p[0] + dir[0], p[1] + dir[1]}]; ok {
+				neighbors[d] = n
+				total++
+			}
+		}
+		kb.keys[key[0]], kb.keys[key[1]] = key, key
+		kb.neighbors[key] = neighbors
+	}
+	kb.degree = float64(total) / float64(len(positions))
+	return kb
+}
+
+// shifted reports whether c is the shifted character of it's key
+func (kb *keyboard) shifted(c byte) bool {
+	key := kb.keys[c]
+	return key != "" && key[0] != key[1] && key[1] == c
+}
+
+// next returns direction from key of a to key of b, -1 if not adjacent
+func (kb *keyboard) next(a, b byte) int {
+	for d, n := range kb.neighbors[kb.keys[a]] {
+		if n != "" && strings.IndexByte(n, b) >= 0 {
+			return d
+		}
+	}
+	return -1
+}
+
+var keyboards = []*keyboard{
+	newKeyboard(qwertyRows, true),
+	newKeyboard(keypadRows, false),
+}
+
+func spatialMatch(password string) []match {
+	var matches []match
+	for _, kb := range keyboards {
+		for i := 0; i+2 < len(password); {
+			j, direction, turns, shifted := i+1, -1, 0, 0
+			if kb.shifted(password[i]) {
+				shifted++
+			}
+			for ; j < len(password); j++ {
+				d := kb.next(password[j-1], password[j])
+				if d < 0 {
+					break
+				}
+				if d != direction {
+					turns++
+					direction = d
+				}
+				if kb.shifted(password[j]) {
+					shifted++
+				}
+			}
+			if j-i > 2 {
+				m := match{pattern: patternSpatial, i: i, j: j - 1, token: password[i:j], turns: turns}
+				m.guesses = kb.guesses(len(m.token), turns, shifted)
+				matches = append(matches, m)
+			}
+			i = j
+		}
+	}
+	return matches
+}
+
+// guesses returns number of guesses of keyboard walks of length n with turns
+func (kb *keyboard) guesses(n, turns, shifted int) float64 {
+	guesses := 0.0
+	for i := 2; i <= n; i++ {
+		for j := 1; j <= turns && j <= i-1; j++ {
+			guesses += binomial(i-1, j-1) * float64(kb.starts) * math.Pow(kb.degree, float64(j))
+		}
+	}
+	if shifted > 0 {
+		if shifted == n {
+			guesses *= 2
+		} else {
+			guesses *= sumBinomials(shifted, n-shifted)
+		}
+	}
+	return guesses
+}
+
+//--------
+// repeat
+//--------
+
+func repeatMatch(password string, dicts []dictionary) []match {
+	var matches []match
+	n := len(password)
+	for i := 0; i < n; {
+		length, size := 0, 0
+		for b := 1; i+2*b <= n; b++ {
+			count := 1
+			for i+(count+1)*b <= n && password[i+count*b:i+(count+1)*b] == password[i:i+b] {
+				count++
+			}
+			if count > 1 && count*b > length {
+				length, size = count*b, b
+			}
+		}
+		if length == 0 {
+			i++
+			continue
+		}
+		m := match{pattern: patternRepeat, i: i, j: i + length - 1, token: password[i : i+length], base: password[i : i+size]}
+		m.guesses = math.Pow(10, estimate(m.base, dicts).log10) * float64(length/size)
+		matches = append(matches, m)
+		i += length
+	}
+	return matches
+}
+
+//----------
+// sequence
+//----------
+
+// charClass returns class of c for sequences, 0 if it's not a letter or digit
+func charClass(c byte) byte {
+	switch {
+	case 'a' <= c && c <= 'z':
+		return ClassLower
+	case 'A' <= c && c <= 'Z':
+		return ClassUpper
+	case '0' <= c && c <= '9':
+		return ClassDigit
+	}
+	return 0
+}
+
+func sequenceMatch(password string) []match {
+	var matches []match
+	n := len(password)
+	for i := 0; i+2 < n; {
+		delta := int(password[i+1]) - int(password[i])
+		j := i + 1
+		for j+1 < n && int(password[j+1])-int(password[j]) == delta {
+			j++
+		}
+		class, same := charClass(password[i]), true
+		for k := i + 1; k <= j; k++ {
+			same = same && charClass(password[k]) == class
+		}
+		if same && class != 0 && delta != 0 && delta >= -5 && delta <= 5 && j-i >= 2 {
+			m := match{pattern: patternSequence, i: i, j: j, token: password[i : j+1]}
+			base := 26.0
+			if strings.IndexByte("aAzZ019", password[i]) >= 0 {
+				base = 4
+			} else if class == ClassDigit {
+				base = 10
+			}
+			if delta < 0 {
+				base *= 2
+			}
+			m.guesses = base * float64(len(m.token))
+			matches = append(matches, m)
+		}
+		i = j
+	}
+	return matches
+}
+
+//------
+// date
+//------
+
+// minYearSpace is the minimum distance of guessed years to the current year
+const minYearSpace = 20
+
+func yearSpace(year int) float64 {
+	space := year - time.Now().Year()
+	if space < 0 {
+		space = -space
+	}
+	if space < minYearSpace {
+		space = minYearSpace
+	}
+	return float64(space)
+}
+
+// parseDayMonthYear returns year of the most recent valid date of parts, which
+// is day, month and year in any order with the year first or last
+func parseDayMonthYear(parts [3]string) (int, bool) {
+	best, found := 0, false
+	try := func(y, m, d string) {
+		if len(y) != 2 && len(y) != 4 || len(m) > 2 || len(d) > 2 {
+			return
+		}
+		year, _ := strconv.Atoi(y)
+		month, _ := strconv.Atoi(m)
+		day, _ := strconv.Atoi(d)
+		if len(y) == 2 {
+			if year > 50 {
+				year += 1900
+			} else {
+				year += 2000
+			}
+		} else if year < 1000 || year > 2050 {
+			return
+		}
+		if month < 1 || month > 12 || day < 1 || day > 31 {
+			return
+		}
+		if !found || yearSpace(year) < yearSpace(best) {
+			best, found = year, true
+		}
+	}
+	a, b, c := parts[0], parts[1], parts[2]
+	try(a, b, c)
+	try(a, c, b)
+	try(c, a, b)
+	try(c, b, a)
+	return best, found
+}
+
+func isDateSeparator(c byte) bool {
+	return strings.IndexByte(" /\\_.-", c) >= 0
+}
+
+func dateMatch(password string) []match {
+	var matches []match
+	n := len(password)
+	for i := 0; i+4 <= n; i++ {
+		if s := password[i : i+4]; isDigits(s) && (s[:2] == "19" || s[:2] == "20") {
+			year, _ := strconv.Atoi(s)
+			matches = append(matches, match{pattern: patternYear, i: i, j: i + 3, token: s, guesses: yearSpace(year)})
+		}
+		// dates without separator, e.g. 13051990
+		for j := i + 4; j <= n && j <= i+8; j++ {
+			token := password[i:j]
+			if !isDigits(token) {
+				break
+			}
+			best, found := 0, false
+			for k1 := 1; k1 < len(token)-1; k1++ {
+				for k2 := k1 + 1; k2 < len(token); k2++ {
+					year, ok := parseDayMonthYear([3]string{token[:k1], token[k1:k2], token[k2:]})
+					if ok && (!found || yearSpace(year) < yearSpace(best)) {
+						best, found = year, true
+					}
+				}
+			}
+			if found {
+				matches = append(matches, match{pattern: patternDate, i: i, j: j - 1, token: token, guesses: yearSpace(best) * 365})
+			}
+		}
+		// dates with separator, e.g. 13/05/1990
+		for j := i + 6; j <= n && j <= i+10; j++ {
+			token := password[i:j]
+			k1 := strings.IndexFunc(token, func(r rune) bool { return r < '0' || r > '9' })
+			if k1 <= 0 || !isDateSeparator(token[k1]) {
+				continue
+			}
+			k2 := strings.IndexByte(token[k1+1:], token[k1]) + k1 + 1
+			if k2 == k1 {
+				continue
+			}
+			parts := [3]string{token[:k1], token[k1+1 : k2], token[k2+1:]}
+			if !isDigits(parts[0]) || !isDigits(parts[1]) || !isDigits(parts[2]) {
+				continue
+			}
+			if year, ok := parseDayMonthYear(parts); ok {
+				matches = append(matches, match{pattern: patternDate, i: i, j: j - 1, token: token, guesses: yearSpace(year) * 365 * 4})
+			}
+		}
+	}
+	return matches
+}
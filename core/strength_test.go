@@ -0,0 +1,58 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestEstimateStrength(t *testing.T) {
+	for _, tt := range []struct {
+		password string
+		pattern  string // pattern of the only match, empty if not checked
+		maxScore int
+	}{
+		{"password", patternDictionary, 0},
+		{"p@ssw0rd", patternDictionary, 0},
+		{"drowssap", patternDictionary, 0},
+		{"qwertyuiop", patternDictionary, 0},
+		{"kjhgfdsa", patternSpatial, 1},
+		{"aaaaaaaaaa", patternRepeat, 0},
+		{"abcabcabc", patternRepeat, 0},
+		{"abcdefgh", patternSequence, 0},
+		{"97531", patternSequence, 0},
+		{"13051990", patternDate, 1},
+		{"13/05/1990", patternDate, 1},
+		{"alice", patternDictionary, 0},
+		{"alice2024", "", 1},
+		{"x9$Kq!7Lz@2Pw#Rt", "", 4},
+	} {
+		s := EstimateStrength(tt.password, "alice@example.com")
+		if s.Score > tt.maxScore {
+			t.Errorf("%s: want score at most %d, got %d", tt.password, tt.maxScore, s.Score)
+		}
+		if tt.maxScore < 3 && s.Warning == "" {
+			t.Errorf("%s: want warning, got empty", tt.password)
+		}
+		if tt.pattern == "" {
+			continue
+		}
+		e := estimate(tt.password, dictionaries([]string{"alice@example.com"}))
+		if len(e.sequence) != 1 || e.sequence[0].pattern != tt.pattern {
+			t.Errorf("%s: want the only match %s, got %v", tt.password, tt.pattern, e.sequence)
+		}
+	}
+	if s := EstimateStrength("x9$Kq!7Lz@2Pw#Rt"); s.Score != 4 || s.Warning != "" || s.CrackTime() != "centuries" {
+		t.Errorf("random password: want score 4 without warning, got %v", s)
+	}
+}
+
+func TestCheckStrength(t *testing.T) {
+	if err := CheckStrength("letmein", 3); err == nil {
+		t.Errorf("CheckStrength letmein want error, got nil")
+	}
+	if err := CheckStrength("x9$Kq!7Lz@2Pw#Rt", 3); err != nil {
+		t.Errorf("CheckStrength want nil, got %v", err)
+	}
+	if err := CheckStrength("letmein", 0); err != nil {
+		t.Errorf("CheckStrength with min score 0 want nil, got %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package core
+
+import (
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mkideal/pkg/textutil"
+)
+
+var (
+	tagHeader   = []string{"TAG", "COUNT"}
+	queryHeader = []string{"ID", "CATEGORY", "ACCOUNT", "SITE"}
+)
+
+// query finds passwords by word for bulk operations like Find, except that ID
+// matches only by prefix and @ALIAS finds only the aliased password
+func (box *Box) query(word string) ([]*Password, error) {
+	if strings.HasPrefix(word, AliasPrefix) {
+		return box.findPasswords([]string{word}, false)
+	}
+	passwords := box.find(func(pw *Password) bool {
+		return strings.HasPrefix(pw.ID, word) || pw.matchFields(word)
+	})
+	if len(passwords) == 0 {
+		return nil, newErrPasswordNotFound(word)
+	}
+	return passwords, nil
+}
+
+// Query writes passwords found by word for bulk operations, e.g. Move and AddTags,
+// to specified writer, and returns number of these passwords
+func (box *Box) Query(w io.Writer, word string) (int, error) {
+	box.RLock()
+	defer box.RUnlock()
+	if box.masterPassword == "" {
+		return 0, errEmptyMasterPassword
+	}
+	passwords, err := box.query(word)
+	if err != nil {
+		return 0, err
+	}
+	var table textutil.StringMatrix
+	for _, pw := range passwords {
+		table = append(table, []string{
+			pw.ShortID(),
+			pw.Category,
+			shorten(pw.PlainAccount, 32),
+			pw.Site,
+		})
+	}
+	textutil.WriteTable(w, textutil.AddTableHeader(table, queryHeader), box.colorID(w, true))
+	return len(passwords), nil
+}
+
+// AddTags adds tags to passwords found by word, returns ids of changed passwords
+func (box *Box) AddTags(word string, tags []string) ([]string, error) {
+	return box.editTags(word, func(pw *Password) bool {
+		changed := false
+		for _, tag := range tags {
+			if !pw.hasTag(tag) {
+				pw.Tags = append(pw.Tags, tag)
+				changed = true
+			}
+		}
+		return changed
+	})
+}
+
+// RemoveTags removes tags from passwords found by word, returns ids of changed passwords
+func (box *Box) RemoveTags(word string, tags []string) ([]string, error) {
+	return box.editTags(word, func(pw *Password) bool {
+		kept := make([]string, 0, len(pw.Tags))
+		for _, tag := range pw.Tags {
+			if !stringsContains(tags, tag) {
+				kept = append(kept, tag)
+			}
+		}
+		changed := len(kept) != len(pw.Tags)
+		pw.Tags = kept
+		return changed
+	})
+}
+
+func (box *Box) editTags(word string, edit func(*Password) bool) ([]string, error) {
+	box.Lock()
+	defer box.Unlock()
+	if box.masterPassword == "" {
+		return nil, errEmptyMasterPassword
+	}
+	passwords, err := box.query(word)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, pw := range passwords {
+		if edit(pw) {
+			ids = append(ids, pw.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+	return ids, box.save()
+}
+
+// ListTags writes all tags and number of passwords which have the tag to specified writer
+func (box *Box) ListTags(w io.Writer) error {
+	box.RLock()
+	defer box.RUnlock()
+	if box.masterPassword == "" {
+		return errEmptyMasterPassword
+	}
+	counts := map[string]int{}
+	for _, pw := range box.passwords {
+		for _, tag := range pw.Tags {
+			counts[tag]++
+		}
+	}
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+	var table textutil.StringMatrix
+	for _, tag := range tags {
+		table = append(table, []string{tag, strconv.Itoa(counts[tag])})
+	}
+	textutil.WriteTable(w, textutil.AddTableHeader(table, tagHeader), nil)
+	return nil
+}
+
+func (pw *Password) hasTag(tag string) bool {
+	return stringsContains(pw.Tags, tag)
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// countRepository counts calls of Save
+type countRepository struct {
+	BoxRepository
+	saves int
+}
+
+func (repo *countRepository) Save(data []byte) error {
+	repo.saves++
+	return repo.BoxRepository.Save(data)
+}
+
+func TestTags(t *testing.T) {
+	repo := &countRepository{BoxRepository: NewMemRepository([]byte{})}
+	box := NewBox(repo)
+	box.masterPassword = "123456"
+	box.passwords = map[string]*Password{
+		"1234567": NewPassword("git", "github", "password", ""),
+		"1234568": NewPassword("git", "gitlab", "password", ""),
+		"1234569": NewPassword("email", "user", "password", ""),
+	}
+	for id, pw := range box.passwords {
+		pw.ID = id
+	}
+	box.passwords["1234567"].Tags = []string{"work"}
+
+	if ids, err := box.AddTags("git", []string{"vcs", "work"}); err != nil || len(ids) != 2 {
+		t.Errorf("AddTags want 2 passwords changed, got %v, %v", ids, err)
+	}
+	if repo.saves != 1 {
+		t.Errorf("AddTags want 1 save, got %d", repo.saves)
+	}
+	if got := box.passwords["1234567"].Tags; !stringsEqual(got, []string{"work", "vcs"}) {
+		t.Errorf("tags want [work vcs], got %v", got)
+	}
+	if ids, err := box.RemoveTags("gitlab", []string{"work"}); err != nil || !stringsEqual(ids, []string{"1234568"}) {
+		t.Errorf("RemoveTags want [1234568], got %v, %v", ids, err)
+	}
+	if _, err := box.AddTags("not_found", []string{"x"}); err == nil {
+		t.Errorf("AddTags not_found want error, got nil")
+	}
+	// ID matches only by prefix
+	if _, err := box.AddTags("4567", []string{"x"}); err == nil {
+		t.Errorf("AddTags by ID substring want error, got nil")
+	}
+	if ids, err := box.AddTags("1234567", []string{"x"}); err != nil || !stringsEqual(ids, []string{"1234567"}) {
+		t.Errorf("AddTags by ID prefix want [1234567], got %v, %v", ids, err)
+	}
+	var query bytes.Buffer
+	if n, err := box.Query(&query, "git"); err != nil || n != 2 || !strings.Contains(query.String(), "gitlab") || strings.Contains(query.String(), "password") {
+		t.Errorf("Query want 2 passwords without secrets, got %d, %v:\n%s", n, err, query.String())
+	}
+
+	var buf bytes.Buffer
+	if err := box.ListTags(&buf); err != nil {
+		t.Fatalf("ListTags error: %v", err)
+	}
+	if out := buf.String(); strings.Index(out, "vcs") > strings.Index(out, "work") {
+		t.Errorf("ListTags want vcs(2) before work(1), got\n%s", out)
+	}
+
+	repo.saves = 0
+	if ids, err := box.Move("git", "code/vcs/"); err != nil || len(ids) != 2 || repo.saves != 1 {
+		t.Errorf("Move want 2 passwords moved with 1 save, got %v, %v, %d saves", ids, err, repo.saves)
+	}
+	if got := box.passwords["1234568"].Category; got != "code/vcs" {
+		t.Errorf("category want code/vcs, got %s", got)
+	}
+}
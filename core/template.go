@@ -0,0 +1,149 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/mkideal/pkg/textutil"
+)
+
+var templateHeader = []string{"NAME", "FIELDS", "DESCRIPTION"}
+
+// ParseTemplates parses user-defined kinds from JSON data which is an array of Kind
+func ParseTemplates(data []byte) ([]*Kind, error) {
+	var templates []*Kind
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, err
+	}
+	for _, tpl := range templates {
+		if err := tpl.check(); err != nil {
+			return nil, err
+		}
+	}
+	return templates, nil
+}
+
+// check checks whether the user-defined kind is well-formed
+func (kind *Kind) check() error {
+	if kind.Name == "" {
+		return fmt.Errorf("template name is empty")
+	}
+	if _, err := LookupKind(kind.Name); err == nil {
+		return fmt.Errorf("template %s: conflict with builtin kind", kind.Name)
+	}
+	names := map[string]bool{}
+	for i := range kind.Fields {
+		spec := &kind.Fields[i]
+		if spec.Name == "" {
+			return fmt.Errorf("template %s: field name is empty", kind.Name)
+		}
+		if names[spec.Name] {
+			return fmt.Errorf("template %s: duplicated field %s", kind.Name, spec.Name)
+		}
+		names[spec.Name] = true
+		if spec.Type == "" {
+			spec.Type = FieldText
+		}
+		switch spec.Type {
+		case FieldText, FieldInt, FieldPort, FieldDate, FieldMonth, FieldCard, FieldCVV, FieldList, FieldSSHKey:
+		default:
+			return fmt.Errorf("template %s: field %s has unknown type %s", kind.Name, spec.Name, spec.Type)
+		}
+	}
+	return nil
+}
+
+// LookupKind finds kind by name from builtin kinds and templates
+func (box *Box) LookupKind(name string) (*Kind, error) {
+	box.RLock()
+	defer box.RUnlock()
+	return box.lookupKind(name)
+}
+
+func (box *Box) lookupKind(name string) (*Kind, error) {
+	if kind, err := LookupKind(name); err == nil {
+		return kind, nil
+	}
+	for _, tpl := range box.store.Templates {
+		if tpl.Name == name {
+			return tpl, nil
+		}
+	}
+	return nil, newErrUnknownKind(name)
+}
+
+// AddTemplates adds templates to box, template which has same name will be replaced
+func (box *Box) AddTemplates(templates []*Kind) error {
+	box.Lock()
+	defer box.Unlock()
+	if box.masterPassword == "" {
+		return errEmptyMasterPassword
+	}
+	for _, tpl := range templates {
+		if err := tpl.check(); err != nil {
+			return err
+		}
+		replaced := false
+		for i := range box.store.Templates {
+			if box.store.Templates[i].Name == tpl.Name {
+				box.store.Templates[i] = tpl
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			box.store.Templates = append(box.store.Templates, tpl)
+		}
+	}
+	return box.save()
+}
+
+// RemoveTemplate removes template by name
+func (box *Box) RemoveTemplate(name string) error {
+	box.Lock()
+	defer box.Unlock()
+	if box.masterPassword == "" {
+		return errEmptyMasterPassword
+	}
+	for i, tpl := range box.store.Templates {
+		if tpl.Name == name {
+			for _, pw := range box.passwords {
+				if pw.Kind == name {
+					return fmt.Errorf("template %s is used by password %s", name, pw.ShortID())
+				}
+			}
+			box.store.Templates = append(box.store.Templates[:i], box.store.Templates[i+1:]...)
+			return box.save()
+		}
+	}
+	return newErrUnknownKind(name)
+}
+
+// ListKinds writes builtin kinds and templates to specified writer
+func (box *Box) ListKinds(w io.Writer) {
+	box.RLock()
+	defer box.RUnlock()
+	templates := make([]*Kind, len(box.store.Templates))
+	copy(templates, box.store.Templates)
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+
+	var table textutil.StringMatrix
+	for _, kind := range append(BuiltinKinds(), templates...) {
+		fields := make([]string, 0, len(kind.Fields))
+		for _, spec := range kind.Fields {
+			field := spec.Name + ":" + string(spec.Type)
+			if spec.Required {
+				field += "*"
+			}
+			if spec.Secret {
+				field += "!"
+			}
+			fields = append(fields, field)
+		}
+		table = append(table, []string{kind.Name, strings.Join(fields, ","), kind.Desc})
+	}
+	textutil.WriteTable(w, textutil.AddTableHeader(table, templateHeader), nil)
+}
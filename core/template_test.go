@@ -0,0 +1,59 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestParseTemplates(t *testing.T) {
+	for _, tt := range []struct {
+		data string
+		ok   bool
+	}{
+		{`[{"Name":"vpn","Fields":[{"Name":"server","Required":true},{"Name":"password","Secret":true}]}]`, true},
+		{`[{"Name":"","Fields":[]}]`, false},
+		{`[{"Name":"card","Fields":[]}]`, false},
+		{`[{"Name":"vpn","Fields":[{"Name":"server"},{"Name":"server"}]}]`, false},
+		{`[{"Name":"vpn","Fields":[{"Name":"server","Type":"unknown"}]}]`, false},
+		{`{}`, false},
+	} {
+		_, err := ParseTemplates([]byte(tt.data))
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseTemplates(%s) want ok=%v, got error %v", tt.data, tt.ok, err)
+		}
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	box := NewBox(NewMemRepository([]byte{}))
+	box.masterPassword = "123456"
+	templates, err := ParseTemplates([]byte(`[{"Name":"vpn","Fields":[{"Name":"server","Required":true},{"Name":"password","Secret":true,"Required":true}]}]`))
+	if err != nil {
+		t.Fatalf("ParseTemplates error: %v", err)
+	}
+	if err := box.AddTemplates(templates); err != nil {
+		t.Fatalf("AddTemplates error: %v", err)
+	}
+	if kind, err := box.LookupKind("vpn"); err != nil || kind.Fields[0].Type != FieldText {
+		t.Errorf("LookupKind vpn want text field, got %v, %v", kind, err)
+	}
+
+	pw := NewPassword("work", "", "password", "")
+	pw.Kind = "vpn"
+	if _, _, err := box.Add(pw); err == nil {
+		t.Errorf("add password without server want error, got nil")
+	}
+	pw.SetField("server", "vpn.example.com")
+	if _, _, err := box.Add(pw); err != nil {
+		t.Fatalf("add password error: %v", err)
+	}
+	if pw.Fields[0].Type != FieldText {
+		t.Errorf("field type want %s, got %s", FieldText, pw.Fields[0].Type)
+	}
+	if err := box.RemoveTemplate("vpn"); err == nil {
+		t.Errorf("remove used template want error, got nil")
+	}
+	delete(box.passwords, pw.ID)
+	if err := box.RemoveTemplate("vpn"); err != nil {
+		t.Errorf("RemoveTemplate want nil, got %v", err)
+	}
+}
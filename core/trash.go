@@ -0,0 +1,161 @@
+package core
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mkideal/pkg/textutil"
+)
+
+var trashHeader = []string{"ID", "CATEGORY", "ACCOUNT", "DELETED_AT"}
+
+// discard removes password from box, the password is moved to trash unless permanent is true
+func (box *Box) discard(pw *Password, permanent bool) {
+	delete(box.passwords, pw.ID)
+	if !permanent {
+		pw.DeletedAt = time.Now().Unix()
+		box.trash[pw.ID] = pw
+	}
+}
+
+// purgeTrash removes passwords which stay in trash longer than TrashMaxAge
+func (box *Box) purgeTrash(now time.Time) []string {
+	if box.store.TrashMaxAge <= 0 {
+		return nil
+	}
+	return box.emptyTrash(now.Add(-time.Duration(box.store.TrashMaxAge) * time.Second))
+}
+
+// emptyTrash removes passwords which deleted before specified time from trash
+func (box *Box) emptyTrash(before time.Time) []string {
+	ids := []string{}
+	for id, pw := range box.trash {
+		if pw.DeletedAt <= before.Unix() {
+			ids = append(ids, id)
+			delete(box.trash, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// EmptyTrash permanently removes passwords which stay in trash longer than
+// specified duration, 0 means removes all
+func (box *Box) EmptyTrash(olderThan time.Duration) ([]string, error) {
+	box.Lock()
+	defer box.Unlock()
+	if box.masterPassword == "" {
+		return nil, errEmptyMasterPassword
+	}
+	ids := box.emptyTrash(time.Now().Add(-olderThan))
+	if len(ids) == 0 {
+		return ids, nil
+	}
+	return ids, box.save()
+}
+
+// Restore moves passwords from trash back to box by ids
+func (box *Box) Restore(ids []string, all bool) ([]string, error) {
+	box.Lock()
+	defer box.Unlock()
+	if box.masterPassword == "" {
+		return nil, errEmptyMasterPassword
+	}
+	passwords := make([]*Password, 0, len(ids))
+	for _, id := range ids {
+		found := passwordPtrSlice{}
+		if pw, ok := box.trash[id]; ok {
+			found = append(found, pw)
+		} else {
+			for _, pw := range box.trash {
+				if strings.HasPrefix(pw.ID, id) {
+					found = append(found, pw)
+				}
+			}
+			sort.Sort(found)
+		}
+		if len(found) == 0 {
+			return nil, newErrPasswordNotFound(id)
+		}
+		if len(found) > 1 && !all {
+			return nil, newErrAmbiguous(found)
+		}
+		passwords = append(passwords, found...)
+	}
+	// aliases must stay unique, the alias may be taken while password in trash
+	aliases := make(map[string]*Password)
+	for _, pw := range passwords {
+		if err := box.checkAlias(pw); err != nil {
+			return nil, err
+		}
+		if pw.Alias == "" {
+			continue
+		}
+		if other, ok := aliases[pw.Alias]; ok && other.ID != pw.ID {
+			return nil, newErrInvalidAlias(pw.Alias, "used by "+other.ShortID())
+		}
+		aliases[pw.Alias] = pw
+	}
+	restored := make([]string, 0, len(passwords))
+	for _, pw := range passwords {
+		if _, ok := box.trash[pw.ID]; !ok {
+			continue
+		}
+		delete(box.trash, pw.ID)
+		pw.DeletedAt = 0
+		box.passwords[pw.ID] = pw
+		restored = append(restored, pw.ID)
+	}
+	return restored, box.save()
+}
+
+// ListTrash writes passwords in trash to specified writer
+func (box *Box) ListTrash(w io.Writer) error {
+	box.RLock()
+	defer box.RUnlock()
+	if box.masterPassword == "" {
+		return errEmptyMasterPassword
+	}
+	var table textutil.StringMatrix
+	for _, pw := range sortedValues(box.trash) {
+		table = append(table, []string{
+			pw.ShortID(),
+			pw.Category,
+			shorten(pw.PlainAccount, 32),
+			time.Unix(pw.DeletedAt, 0).Format(time.RFC3339),
+		})
+	}
+	textutil.WriteTable(w, textutil.AddTableHeader(table, trashHeader), box.colorID(w, true))
+	return nil
+}
+
+// TrashMaxAge returns max age of passwords in trash, 0 means never purged
+func (box *Box) TrashMaxAge() time.Duration {
+	box.RLock()
+	defer box.RUnlock()
+	return time.Duration(box.store.TrashMaxAge) * time.Second
+}
+
+// SetTrashMaxAge sets max age of passwords in trash, 0 means never purged
+func (box *Box) SetTrashMaxAge(maxAge time.Duration) error {
+	box.Lock()
+	defer box.Unlock()
+	if box.masterPassword == "" {
+		return errEmptyMasterPassword
+	}
+	box.store.TrashMaxAge = int64(maxAge / time.Second)
+	box.purgeTrash(time.Now())
+	return box.save()
+}
+
+// sortedValues returns copies of passwords sorted by ID
+func sortedValues(m map[string]*Password) []Password {
+	passwords := make([]Password, 0, len(m))
+	for _, pw := range m {
+		passwords = append(passwords, *pw)
+	}
+	sort.Sort(passwordSlice(passwords))
+	return passwords
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrash(t *testing.T) {
+	box := NewBox(NewMemRepository([]byte{}))
+	box.masterPassword = "123456"
+	box.passwords = map[string]*Password{
+		"1234567": NewPassword("category", "account", "password", "site"),
+		"1234568": NewPassword("CATEGORY", "ACCOUNT", "PASSWORD", "SITE"),
+	}
+	for id, pw := range box.passwords {
+		pw.ID = id
+	}
+
+	if _, err := box.Remove([]string{"1234567"}, false, false, false); err != nil {
+		t.Fatalf("Remove error: %v", err)
+	}
+	if _, ok := box.trash["1234567"]; !ok || len(box.passwords) != 1 {
+		t.Errorf("removed password should be moved to trash")
+	}
+	if _, err := box.Remove([]string{"1234568"}, false, true, false); err != nil {
+		t.Fatalf("Remove permanently error: %v", err)
+	}
+	if _, ok := box.trash["1234568"]; ok {
+		t.Errorf("password removed permanently should not be moved to trash")
+	}
+
+	// save and load box from the repository
+	data, err := box.repo.Load()
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	box2 := NewBox(NewMemRepository(data))
+	box2.masterPassword = "123456"
+	if err := box2.load(); err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	if pw, ok := box2.trash["1234567"]; !ok || pw.PlainPassword != "password" {
+		t.Fatalf("password in trash want loaded, got %v", pw)
+	}
+
+	if ids, err := box2.Restore([]string{"123"}, false); err != nil || !stringsEqual(ids, []string{"1234567"}) {
+		t.Errorf("Restore want [1234567], got %v, %v", ids, err)
+	}
+	if pw, ok := box2.passwords["1234567"]; !ok || pw.DeletedAt != 0 {
+		t.Errorf("restored password want in box, got %v", pw)
+	}
+
+	if _, err := box2.Clear(false); err != nil {
+		t.Fatalf("Clear error: %v", err)
+	}
+	box2.trash["1234567"].DeletedAt = time.Now().Add(-48 * time.Hour).Unix()
+	if ids := box2.purgeTrash(time.Now()); len(ids) != 0 {
+		t.Errorf("purgeTrash without max age want nothing, got %v", ids)
+	}
+	if err := box2.SetTrashMaxAge(24 * time.Hour); err != nil {
+		t.Fatalf("SetTrashMaxAge error: %v", err)
+	}
+	if len(box2.trash) != 0 {
+		t.Errorf("trash want purged, got %d passwords", len(box2.trash))
+	}
+}
+
+func TestRestoreTakenAlias(t *testing.T) {
+	box := NewBox(NewMemRepository([]byte{}))
+	box.masterPassword = "123456"
+	box.passwords = map[string]*Password{
+		"1234567": NewPassword("category", "account", "password", "site"),
+		"1234568": NewPassword("CATEGORY", "ACCOUNT", "PASSWORD", "SITE"),
+	}
+	for id, pw := range box.passwords {
+		pw.ID = id
+	}
+	box.passwords["1234567"].Alias = "work"
+
+	if _, err := box.Remove([]string{"1234567"}, false, false, false); err != nil {
+		t.Fatalf("Remove error: %v", err)
+	}
+	box.passwords["1234568"].Alias = "work"
+	if _, err := box.Restore([]string{"1234567"}, false); err == nil {
+		t.Errorf("Restore with taken alias want error, got nil")
+	}
+	if _, ok := box.trash["1234567"]; !ok {
+		t.Errorf("password want kept in trash when restore fails")
+	}
+	box.passwords["1234568"].Alias = ""
+	if ids, err := box.Restore([]string{"1234567"}, false); err != nil || !stringsEqual(ids, []string{"1234567"}) {
+		t.Errorf("Restore want [1234567], got %v, %v", ids, err)
+	}
+}
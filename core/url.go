@@ -0,0 +1,177 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// URLMatch represents rule of matching URL
+type URLMatch string
+
+// Supported URL match rules, ordered by strength from strong to weak
+const (
+	URLMatchExact  URLMatch = "exact"  // whole URL equals
+	URLMatchPrefix URLMatch = "prefix" // URL starts with
+	URLMatchRegex  URLMatch = "regex"  // URL matches the regular expression
+	URLMatchHost   URLMatch = "host"   // host of URL equals
+	URLMatchDomain URLMatch = "domain" // base domain of URL equals, e.g. login.github.com matches github.com
+)
+
+var urlMatchStrength = map[URLMatch]int{
+	URLMatchExact:  5,
+	URLMatchPrefix: 4,
+	URLMatchRegex:  3,
+	URLMatchHost:   2,
+	URLMatchDomain: 1,
+}
+
+// URLRule represents an URL of password and it's match rule
+type URLRule struct {
+	URL   string
+	Match URLMatch
+}
+
+// String returns the rule formatted as MATCH:URL
+func (rule URLRule) String() string {
+	return string(rule.Match) + ":" + rule.URL
+}
+
+// ParseURLRule parses rule formatted as [MATCH:]URL, default MATCH is domain
+func ParseURLRule(s string) (URLRule, error) {
+	rule := URLRule{URL: s, Match: URLMatchDomain}
+	if i := strings.Index(s, ":"); i > 0 {
+		if _, ok := urlMatchStrength[URLMatch(s[:i])]; ok {
+			rule.Match = URLMatch(s[:i])
+			rule.URL = s[i+1:]
+		}
+	}
+	if rule.URL == "" {
+		return rule, fmt.Errorf("url of rule %s is empty", s)
+	}
+	if rule.Match == URLMatchRegex {
+		if _, err := regexp.Compile(rule.URL); err != nil {
+			return rule, err
+		}
+	} else if _, err := parseURL(rule.URL); err != nil {
+		return rule, err
+	}
+	return rule, nil
+}
+
+// parseURL parses URL, scheme http is used if it's absent
+func parseURL(rawURL string) (*url.URL, error) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("host of url %s is empty", rawURL)
+	}
+	return u, nil
+}
+
+// match returns strength of matching the URL, 0 means not matched
+func (rule URLRule) match(rawURL string, u *url.URL) int {
+	matched := false
+	switch rule.Match {
+	case URLMatchExact:
+		matched = strings.TrimSuffix(rule.URL, "/") == strings.TrimSuffix(rawURL, "/")
+	case URLMatchPrefix:
+		matched = strings.HasPrefix(rawURL, rule.URL)
+	case URLMatchRegex:
+		if re, err := regexp.Compile(rule.URL); err == nil {
+			matched = re.MatchString(rawURL)
+		}
+	case URLMatchHost, URLMatchDomain:
+		ru, err := parseURL(rule.URL)
+		if err != nil {
+			return 0
+		}
+		host, ruleHost := strings.ToLower(u.Hostname()), strings.ToLower(ru.Hostname())
+		if rule.Match == URLMatchHost {
+			matched = host == ruleHost
+		} else {
+			matched = baseDomain(host) == baseDomain(ruleHost)
+		}
+	}
+	if !matched {
+		return 0
+	}
+	// longer prefix is stronger
+	strength := urlMatchStrength[rule.Match] << 10
+	if rule.Match == URLMatchPrefix {
+		if n := len(rule.URL); n < 1<<10 {
+			strength += n
+		} else {
+			strength += 1<<10 - 1
+		}
+	}
+	return strength
+}
+
+// urlRules returns URL rules of password, the Site is used as a domain rule
+func (pw *Password) urlRules() []URLRule {
+	rules := pw.URLs
+	if pw.Site != "" {
+		rules = append([]URLRule{{URL: pw.Site, Match: URLMatchDomain}}, rules...)
+	}
+	return rules
+}
+
+// matchURL returns the strongest strength of matching the URL, 0 means not matched
+func (pw *Password) matchURL(rawURL string, u *url.URL) int {
+	strength := 0
+	for _, rule := range pw.urlRules() {
+		if s := rule.match(rawURL, u); s > strength {
+			strength = s
+		}
+	}
+	return strength
+}
+
+// FindURL finds passwords which apply to the URL, ranked by match strength, and
+// printed passwords are tracked as usage like Find
+func (box *Box) FindURL(w io.Writer, rawURL string, justPassword, justFirst bool) error {
+	box.Lock()
+	defer box.Unlock()
+	if box.masterPassword == "" {
+		return errEmptyMasterPassword
+	}
+	u, err := parseURL(rawURL)
+	if err != nil {
+		return err
+	}
+	strength := map[string]int{}
+	table := box.find(func(pw *Password) bool {
+		strength[pw.ID] = pw.matchURL(rawURL, u)
+		return strength[pw.ID] > 0
+	})
+	sort.SliceStable(table, func(i, j int) bool {
+		return strength[table[i].ID] > strength[table[j].ID]
+	})
+	return box.writeFound(w, table, justPassword, justFirst)
+}
+
+// baseDomain returns registrable domain of host, i.e. public suffix plus one
+// label by the public suffix list, host itself if it's an IP or a public suffix
+func baseDomain(host string) string {
+	host = strings.TrimSuffix(host, ".")
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
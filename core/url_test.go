@@ -0,0 +1,93 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBaseDomain(t *testing.T) {
+	for _, tt := range []struct {
+		host string
+		want string
+	}{
+		{"github.com", "github.com"},
+		{"login.github.com", "github.com"},
+		{"www.bbc.co.uk", "bbc.co.uk"},
+		{"bbc.co.uk", "bbc.co.uk"},
+		{"mkideal.github.io", "mkideal.github.io"},
+		{"login.a.com.pl", "a.com.pl"},
+		{"b.com.pl", "b.com.pl"},
+		{"shop.ltd.uk", "shop.ltd.uk"},
+		{"co.uk", "co.uk"},
+		{"127.0.0.1", "127.0.0.1"},
+		{"localhost", "localhost"},
+	} {
+		if got := baseDomain(tt.host); got != tt.want {
+			t.Errorf("baseDomain(%s) want %s, got %s", tt.host, tt.want, got)
+		}
+	}
+}
+
+func TestParseURLRule(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want URLRule
+		ok   bool
+	}{
+		{"github.com", URLRule{URL: "github.com", Match: URLMatchDomain}, true},
+		{"https://github.com", URLRule{URL: "https://github.com", Match: URLMatchDomain}, true},
+		{"host:login.github.com", URLRule{URL: "login.github.com", Match: URLMatchHost}, true},
+		{"regex:^https://.*\\.corp/", URLRule{URL: "^https://.*\\.corp/", Match: URLMatchRegex}, true},
+		{"regex:(", URLRule{}, false},
+		{"prefix:", URLRule{}, false},
+	} {
+		got, err := ParseURLRule(tt.s)
+		if (err == nil) != tt.ok || (tt.ok && got != tt.want) {
+			t.Errorf("ParseURLRule(%s) want %v, got %v, %v", tt.s, tt.want, got, err)
+		}
+	}
+}
+
+func TestFindURL(t *testing.T) {
+	box := NewBox(NewMemRepository([]byte{}))
+	box.masterPassword = "123456"
+	box.passwords = map[string]*Password{
+		"1234567": NewPassword("git", "domain", "password", "github.com"),
+		"1234568": NewPassword("git", "host", "password", ""),
+		"1234569": NewPassword("git", "prefix", "password", ""),
+		"1234570": NewPassword("git", "digital", "password", "digital.com"),
+	}
+	box.passwords["1234568"].URLs = []URLRule{{URL: "login.github.com", Match: URLMatchHost}}
+	box.passwords["1234569"].URLs = []URLRule{
+		{URL: "https://login.github.com/", Match: URLMatchPrefix},
+		{URL: "https://login.github.com/foo", Match: URLMatchPrefix},
+	}
+	for id, pw := range box.passwords {
+		pw.ID = id
+	}
+	var buf bytes.Buffer
+	if err := box.FindURL(&buf, "https://login.github.com/foo/bar", false, false); err != nil {
+		t.Fatalf("FindURL error: %v", err)
+	}
+	out := buf.String()
+	prefix, host, domain := strings.Index(out, "prefix"), strings.Index(out, "host"), strings.Index(out, "domain")
+	if prefix < 0 || host < 0 || domain < 0 || !(prefix < host && host < domain) {
+		t.Errorf("FindURL want ranked prefix,host,domain, got\n%s", out)
+	}
+	if strings.Contains(out, "digital") {
+		t.Errorf("FindURL should not match digital.com, got\n%s", out)
+	}
+
+	// printing the password counts as usage
+	buf.Reset()
+	if err := box.FindURL(&buf, "https://login.github.com/foo/bar", true, true); err != nil {
+		t.Fatalf("FindURL error: %v", err)
+	}
+	if pw := box.passwords["1234569"]; pw.UseCount != 1 || pw.LastUsedAt == 0 {
+		t.Errorf("FindURL -p want used once, got %d at %d", pw.UseCount, pw.LastUsedAt)
+	}
+	if pw := box.passwords["1234568"]; pw.UseCount != 0 {
+		t.Errorf("FindURL -p want only the first used, got %d", pw.UseCount)
+	}
+}
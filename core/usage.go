@@ -0,0 +1,85 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SortBy represents order of listed passwords
+type SortBy string
+
+// Supported orders
+const (
+	SortByID       SortBy = "id"       // sort by ID
+	SortByRecent   SortBy = "recent"   // favorites first, then most recently used first
+	SortByFrequent SortBy = "frequent" // favorites first, then most frequently used first
+)
+
+// ParseSortBy parses order name, empty name means SortByID
+func ParseSortBy(name string) (SortBy, error) {
+	switch by := SortBy(name); by {
+	case "":
+		return SortByID, nil
+	case SortByID, SortByRecent, SortByFrequent:
+		return by, nil
+	}
+	return "", fmt.Errorf("unknown order %s, must be one of id,recent,frequent", name)
+}
+
+func (by SortBy) less(p1, p2 *Password) bool {
+	if by != SortByID && p1.Favorite != p2.Favorite {
+		return p1.Favorite
+	}
+	switch by {
+	case SortByRecent:
+		if p1.LastUsedAt != p2.LastUsedAt {
+			return p1.LastUsedAt > p2.LastUsedAt
+		}
+	case SortByFrequent:
+		if p1.UseCount != p2.UseCount {
+			return p1.UseCount > p2.UseCount
+		}
+	}
+	return p1.ID < p2.ID
+}
+
+func (by SortBy) sort(passwords []*Password) {
+	sort.SliceStable(passwords, func(i, j int) bool {
+		return by.less(passwords[i], passwords[j])
+	})
+}
+
+func (by SortBy) sortValues(passwords []Password) {
+	sort.SliceStable(passwords, func(i, j int) bool {
+		return by.less(&passwords[i], &passwords[j])
+	})
+}
+
+// touch records an use of passwords
+func (box *Box) touch(passwords []*Password) {
+	now := time.Now().Unix()
+	for _, pw := range passwords {
+		pw.LastUsedAt = now
+		pw.UseCount++
+	}
+}
+
+// SetFavorite marks or unmarks passwords as favorite by ids
+func (box *Box) SetFavorite(ids []string, favorite bool) ([]string, error) {
+	box.Lock()
+	defer box.Unlock()
+	if box.masterPassword == "" {
+		return nil, errEmptyMasterPassword
+	}
+	passwords, err := box.findPasswords(ids, false)
+	if err != nil {
+		return nil, err
+	}
+	changed := make([]string, 0, len(passwords))
+	for _, pw := range passwords {
+		pw.Favorite = favorite
+		changed = append(changed, pw.ID)
+	}
+	return changed, box.save()
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSortBy(t *testing.T) {
+	passwords := []*Password{
+		NewEmptyPassword(),
+		NewEmptyPassword(),
+		NewEmptyPassword(),
+	}
+	for i, pw := range passwords {
+		pw.ID = string(rune('a' + i))
+	}
+	passwords[0].LastUsedAt, passwords[0].UseCount = 100, 1
+	passwords[1].LastUsedAt, passwords[1].UseCount = 50, 5
+	passwords[2].Favorite = true
+
+	for _, tt := range []struct {
+		by   SortBy
+		want string
+	}{
+		{SortByID, "abc"},
+		{SortByRecent, "cab"},
+		{SortByFrequent, "cba"},
+	} {
+		tt.by.sort(passwords)
+		got := ""
+		for _, pw := range passwords {
+			got += pw.ID
+		}
+		if got != tt.want {
+			t.Errorf("sort by %s want %s, got %s", tt.by, tt.want, got)
+		}
+	}
+
+	if _, err := ParseSortBy("unknown"); err == nil {
+		t.Errorf("ParseSortBy unknown want error, got nil")
+	}
+}
+
+func TestUsage(t *testing.T) {
+	box := NewBox(NewMemRepository([]byte{}))
+	box.masterPassword = "123456"
+	box.passwords = map[string]*Password{
+		"1234567": NewPassword("category", "account", "password", "site"),
+		"1234568": NewPassword("CATEGORY", "ACCOUNT", "PASSWORD", "SITE"),
+	}
+	for id, pw := range box.passwords {
+		pw.ID = id
+	}
+	var buf bytes.Buffer
+	if err := box.Find(&buf, "account", true, false, SortByID); err != nil {
+		t.Fatalf("Find error: %v", err)
+	}
+	if err := box.Find(&buf, "ACCOUNT", false, false, SortByID); err != nil {
+		t.Fatalf("Find error: %v", err)
+	}
+	if err := box.Inspect(&buf, []string{"1234567"}, false); err != nil {
+		t.Fatalf("Inspect error: %v", err)
+	}
+	if pw := box.passwords["1234567"]; pw.UseCount != 2 || pw.LastUsedAt == 0 {
+		t.Errorf("1234567 UseCount want 2, got %d", pw.UseCount)
+	}
+	if pw := box.passwords["1234568"]; pw.UseCount != 0 {
+		t.Errorf("1234568 UseCount want 0, got %d", pw.UseCount)
+	}
+
+	if _, err := box.SetFavorite([]string{"1234568"}, true); err != nil || !box.passwords["1234568"].Favorite {
+		t.Errorf("SetFavorite want favorite, got %v", err)
+	}
+}
@@ -6,12 +6,26 @@ import (
 	"fmt"
 	"github.com/labstack/gommon/color"
 	"hash"
+	"io"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type colorable interface {
 	Color() *color.Color
 }
 
+// colorOf returns color of writer, or a disabled color if writer is not colorable
+func colorOf(w io.Writer) *color.Color {
+	if c, ok := w.(colorable); ok {
+		return c.Color()
+	}
+	c := color.New()
+	c.Disable()
+	return c
+}
+
 func md5sum(i interface{}) string {
 	return hashsum(i, md5.New())
 }
@@ -40,3 +54,44 @@ func copyNonEmptyString(dst *string, src string) {
 		*dst = src
 	}
 }
+
+// ParseDuration parses duration string like time.ParseDuration, and
+// additionally supports units d(day) and w(week), e.g. 30d, 2w. Negative
+// durations are rejected
+func ParseDuration(s string) (time.Duration, error) {
+	d, err := parseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative duration %s", s)
+	}
+	return d, nil
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	for unit, scale := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(s, unit) {
+			n, err := strconv.Atoi(strings.TrimSuffix(s, unit))
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %s", s)
+			}
+			return time.Duration(n) * scale, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+// ParseDate parses date formatted as YYYY-MM-DD in local time zone
+func ParseDate(s string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", s, time.Local)
+}
+
+func stringsContains(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"testing"
+	"time"
 )
 
 func TestHash5Sum(t *testing.T) {
@@ -31,3 +32,25 @@ func TestHash5Sum(t *testing.T) {
 		}
 	}
 }
+
+func TestParseDuration(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want time.Duration
+		ok   bool
+	}{
+		{"30d", 30 * 24 * time.Hour, true},
+		{"2w", 14 * 24 * time.Hour, true},
+		{"12h", 12 * time.Hour, true},
+		{"xd", 0, false},
+		{"30", 0, false},
+		{"0d", 0, true},
+		{"-5d", 0, false},
+		{"-1h", 0, false},
+	} {
+		got, err := ParseDuration(tt.s)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseDuration(%s) want %v, got %v, %v", tt.s, tt.want, got, err)
+		}
+	}
+}
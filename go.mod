@@ -8,4 +8,5 @@ require (
 	github.com/mkideal/cli v0.2.2
 	github.com/mkideal/pkg v0.1.2
 	golang.org/x/crypto v0.0.0-20200709230013-948cd5f35899
+	golang.org/x/net v0.0.0-20200707034311-ab3426394381
 )